@@ -8,6 +8,7 @@ package alpaca
 
 import (
 	"fmt"
+	"sync/atomic"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/go-resty/resty/v2"
@@ -35,8 +36,8 @@ func NewAlpaca(clientid uint32, ip string, port int32) *Alpaca {
 
 // Each Alpaca call should have a monotonically incrementing transactionId
 func (a *Alpaca) GetNextTransactionId() uint32 {
-	a.transactionId += 1
-	return a.transactionId
+	// multiple listeners may share the same Alpaca client
+	return atomic.AddUint32(&a.transactionId, 1)
 }
 
 // Generate our QueryString with the default parameters
@@ -262,3 +263,67 @@ func (a *Alpaca) Put(device string, id uint32, api string, form map[string]strin
 	log.Debugf("Alpaca PUT response: %s", spew.Sdump(result))
 	return nil
 }
+
+// PUT which returns a string Value such as commandstring and action
+func (a *Alpaca) PutString(device string, id uint32, api string, form map[string]string) (string, error) {
+	url := a.url(device, id, api)
+	log.Debugf("Alpaca PUT: %v", spew.Sdump(form))
+	resp, err := a.client.R().
+		SetHeader("Content-Type", "application/x-www-form-urlencoded").
+		SetResult(&stringResponse{}).
+		SetFormData(form).
+		Put(url)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.IsError() {
+		a.ErrorNumber = resp.StatusCode()
+		a.ErrorMessage = resp.String()
+	}
+	result := (resp.Result().(*stringResponse))
+	if result.ErrorNumber != 0 {
+		return "", fmt.Errorf("%d: %s", result.ErrorNumber, result.ErrorMessage)
+	}
+	log.Debugf("Alpaca PUT response: %s", spew.Sdump(result))
+	return result.Value, nil
+}
+
+/*
+ * https://ascom-standards.org/api/#/ASCOM%20Methods%20Common%20To%20All%20Devices/put__device_type___device_number__action
+ */
+func (a *Alpaca) PutAction(device string, id uint32, action string, parameters string) (string, error) {
+	form := map[string]string{
+		"Action":              action,
+		"Parameters":          parameters,
+		"ClientID":            fmt.Sprintf("%d", a.ClientId),
+		"ClientTransactionID": fmt.Sprintf("%d", a.GetNextTransactionId()),
+	}
+	return a.PutString(device, id, "action", form)
+}
+
+/*
+ * https://ascom-standards.org/api/#/ASCOM%20Methods%20Common%20To%20All%20Devices/put__device_type___device_number__commandblind
+ */
+func (a *Alpaca) PutCommandBlind(device string, id uint32, command string, raw bool) error {
+	form := map[string]string{
+		"Command":             command,
+		"Raw":                 fmt.Sprintf("%t", raw),
+		"ClientID":            fmt.Sprintf("%d", a.ClientId),
+		"ClientTransactionID": fmt.Sprintf("%d", a.GetNextTransactionId()),
+	}
+	return a.Put(device, id, "commandblind", form)
+}
+
+/*
+ * https://ascom-standards.org/api/#/ASCOM%20Methods%20Common%20To%20All%20Devices/put__device_type___device_number__commandstring
+ */
+func (a *Alpaca) PutCommandString(device string, id uint32, command string, raw bool) (string, error) {
+	form := map[string]string{
+		"Command":             command,
+		"Raw":                 fmt.Sprintf("%t", raw),
+		"ClientID":            fmt.Sprintf("%d", a.ClientId),
+		"ClientTransactionID": fmt.Sprintf("%d", a.GetNextTransactionId()),
+	}
+	return a.PutString(device, id, "commandstring", form)
+}
@@ -0,0 +1,72 @@
+package alpaca
+
+/*
+ * Implements the Alpaca Focuser client API
+ */
+
+import (
+	"fmt"
+)
+
+type Focuser struct {
+	alpaca *Alpaca
+	Id     uint32
+}
+
+func NewFocuser(id uint32, alpaca *Alpaca) *Focuser {
+	f := Focuser{
+		alpaca: alpaca,
+		Id:     id,
+	}
+	return &f
+}
+
+func (f *Focuser) GetName() (string, error) {
+	return f.alpaca.GetName("focuser", f.Id)
+}
+
+func (f *Focuser) GetConnected() (bool, error) {
+	return f.alpaca.GetConnected("focuser", f.Id)
+}
+
+// Absolute focusers move to a position, others move by a number of steps
+func (f *Focuser) GetAbsolute() (bool, error) {
+	return f.alpaca.GetBool("focuser", f.Id, "absolute")
+}
+
+func (f *Focuser) GetIsMoving() (bool, error) {
+	return f.alpaca.GetBool("focuser", f.Id, "ismoving")
+}
+
+func (f *Focuser) GetMaxIncrement() (int32, error) {
+	return f.alpaca.GetInt32("focuser", f.Id, "maxincrement")
+}
+
+func (f *Focuser) GetMaxStep() (int32, error) {
+	return f.alpaca.GetInt32("focuser", f.Id, "maxstep")
+}
+
+// Only valid for absolute focusers
+func (f *Focuser) GetPosition() (int32, error) {
+	return f.alpaca.GetInt32("focuser", f.Id, "position")
+}
+
+func (f *Focuser) PutHalt() error {
+	form := map[string]string{
+		"ClientID":            fmt.Sprintf("%d", f.alpaca.ClientId),
+		"ClientTransactionID": fmt.Sprintf("%d", f.alpaca.GetNextTransactionId()),
+	}
+	err := f.alpaca.Put("focuser", f.Id, "halt", form)
+	return err
+}
+
+// Position for absolute focusers, otherwise the number of steps to move
+func (f *Focuser) PutMove(position int32) error {
+	form := map[string]string{
+		"Position":            fmt.Sprintf("%d", position),
+		"ClientID":            fmt.Sprintf("%d", f.alpaca.ClientId),
+		"ClientTransactionID": fmt.Sprintf("%d", f.alpaca.GetNextTransactionId()),
+	}
+	err := f.alpaca.Put("focuser", f.Id, "move", form)
+	return err
+}
@@ -6,6 +6,7 @@ package alpaca
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/relvacode/iso8601"
@@ -37,6 +38,24 @@ const (
 	AxisTertiary
 )
 
+type DriveRate int32
+
+const (
+	DriveSidereal DriveRate = iota
+	DriveLunar
+	DriveSolar
+	DriveKing
+)
+
+type GuideDirection int
+
+const (
+	GuideNorth GuideDirection = iota
+	GuideSouth
+	GuideEast
+	GuideWest
+)
+
 type Telescope struct {
 	alpaca   *Alpaca
 	Id       uint32
@@ -52,6 +71,11 @@ func NewTelescope(id uint32, tm TrackingMode, alpaca *Alpaca) *Telescope {
 	return &t
 }
 
+// Returns the Alpaca client used to talk to the telescope
+func (t *Telescope) Client() *Alpaca {
+	return t.alpaca
+}
+
 func (t *Telescope) GetName() (string, error) {
 	return t.alpaca.GetName("telescope", t.Id)
 }
@@ -68,6 +92,32 @@ func (t *Telescope) GetSupportedActions() ([]string, error) {
 	return t.alpaca.GetSupportedActions("telescope", t.Id)
 }
 
+// Returns true if the driver lists the action in SupportedActions
+func (t *Telescope) SupportsAction(action string) (bool, error) {
+	actions, err := t.GetSupportedActions()
+	if err != nil {
+		return false, err
+	}
+	for _, a := range actions {
+		if strings.EqualFold(a, action) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (t *Telescope) PutAction(action string, parameters string) (string, error) {
+	return t.alpaca.PutAction("telescope", t.Id, action, parameters)
+}
+
+func (t *Telescope) PutCommandBlind(command string, raw bool) error {
+	return t.alpaca.PutCommandBlind("telescope", t.Id, command, raw)
+}
+
+func (t *Telescope) PutCommandString(command string, raw bool) (string, error) {
+	return t.alpaca.PutCommandString("telescope", t.Id, command, raw)
+}
+
 func (t *Telescope) GetAlignmentMode() (AlignmentMode, error) {
 	mode, err := t.alpaca.GetInt32("telescope", t.Id, "alignmentmode")
 	return AlignmentMode(mode), err
@@ -97,6 +147,18 @@ func (t *Telescope) GetCanFindHome() (bool, error) {
 	return t.alpaca.GetBool("telescope", t.Id, "canfindhome")
 }
 
+func (t *Telescope) GetCanUnpark() (bool, error) {
+	return t.alpaca.GetBool("telescope", t.Id, "canunpark")
+}
+
+func (t *Telescope) GetAtPark() (bool, error) {
+	return t.alpaca.GetBool("telescope", t.Id, "atpark")
+}
+
+func (t *Telescope) GetAtHome() (bool, error) {
+	return t.alpaca.GetBool("telescope", t.Id, "athome")
+}
+
 func (t *Telescope) GetCanSlew() (bool, error) {
 	return t.alpaca.GetBool("telescope", t.Id, "canslew")
 }
@@ -113,6 +175,45 @@ func (t *Telescope) GetCanSlewAltAzAsync() (bool, error) {
 	return t.alpaca.GetBool("telescope", t.Id, "canslewaltazasync")
 }
 
+func (t *Telescope) GetCanPulseGuide() (bool, error) {
+	return t.alpaca.GetBool("telescope", t.Id, "canpulseguide")
+}
+
+func (t *Telescope) GetCanSetTracking() (bool, error) {
+	return t.alpaca.GetBool("telescope", t.Id, "cansettracking")
+}
+
+func (t *Telescope) GetCanSetGuideRates() (bool, error) {
+	return t.alpaca.GetBool("telescope", t.Id, "cansetguiderates")
+}
+
+func (t *Telescope) GetIsPulseGuiding() (bool, error) {
+	return t.alpaca.GetBool("telescope", t.Id, "ispulseguiding")
+}
+
+// Guide rates are in degrees/sec
+func (t *Telescope) GetGuideRateRightAscension() (float64, error) {
+	return t.alpaca.GetFloat64("telescope", t.Id, "guideraterightascension")
+}
+
+func (t *Telescope) GetGuideRateDeclination() (float64, error) {
+	return t.alpaca.GetFloat64("telescope", t.Id, "guideratedeclination")
+}
+
+func (t *Telescope) GetCanSetRightAscensionRate() (bool, error) {
+	return t.alpaca.GetBool("telescope", t.Id, "cansetrightascensionrate")
+}
+
+func (t *Telescope) GetTrackingRate() (DriveRate, error) {
+	rate, err := t.alpaca.GetInt32("telescope", t.Id, "trackingrate")
+	return DriveRate(rate), err
+}
+
+// Offset from sidereal in seconds of RA per sidereal second
+func (t *Telescope) GetRightAscensionRate() (float64, error) {
+	return t.alpaca.GetFloat64("telescope", t.Id, "rightascensionrate")
+}
+
 func (t *Telescope) GetSlewing() (bool, error) {
 	return t.alpaca.GetBool("telescope", t.Id, "slewing")
 }
@@ -125,10 +226,19 @@ func (t *Telescope) GetSiteLongitude() (float64, error) {
 	return t.alpaca.GetFloat64("telescope", t.Id, "sitelongitude")
 }
 
+// meters
+func (t *Telescope) GetSiteElevation() (float64, error) {
+	return t.alpaca.GetFloat64("telescope", t.Id, "siteelevation")
+}
+
 func (t *Telescope) GetTargetDeclination() (float64, error) {
 	return t.alpaca.GetFloat64("telescope", t.Id, "targetdeclination")
 }
 
+func (t *Telescope) GetTargetRightAscension() (float64, error) {
+	return t.alpaca.GetFloat64("telescope", t.Id, "targetrightascension")
+}
+
 func (t *Telescope) GetTargetAltitude() (float64, error) {
 	return t.alpaca.GetFloat64("telescope", t.Id, "targetrightascension")
 }
@@ -201,10 +311,11 @@ func (t *Telescope) PutConnected(connected bool) error {
 	return err
 }
 
-func (t *Telescope) PutMoveAxis(axis AxisType, rate int) error {
+// Rate is in degrees/sec and must be within the limits of GetAxisRates()
+func (t *Telescope) PutMoveAxis(axis AxisType, rate float64) error {
 	form := map[string]string{
 		"Axis":                fmt.Sprintf("%d", axis),
-		"Rate":                fmt.Sprintf("%d", rate),
+		"Rate":                fmt.Sprintf("%g", rate),
 		"ClientID":            fmt.Sprintf("%d", t.alpaca.ClientId),
 		"ClientTransactionID": fmt.Sprintf("%d", t.alpaca.GetNextTransactionId()),
 	}
@@ -234,6 +345,17 @@ func (t *Telescope) PutSlewToCoordinatestAsync(ra float64, dec float64) error {
 	return err
 }
 
+func (t *Telescope) PutSlewToAltAzAsync(az float64, alt float64) error {
+	form := map[string]string{
+		"Azimuth":             fmt.Sprintf("%g", az),
+		"Altitude":            fmt.Sprintf("%g", alt),
+		"ClientID":            fmt.Sprintf("%d", t.alpaca.ClientId),
+		"ClientTransactionID": fmt.Sprintf("%d", t.alpaca.GetNextTransactionId()),
+	}
+	err := t.alpaca.Put("telescope", t.Id, "slewtoaltazasync", form)
+	return err
+}
+
 func (t *Telescope) PutSlewToCoordinates(ra float64, dec float64) error {
 	form := map[string]string{
 		"RightAscension":      fmt.Sprintf("%g", ra),
@@ -255,6 +377,17 @@ func (t *Telescope) PutSiteLatitude(lat float64) error {
 	return err
 }
 
+// meters
+func (t *Telescope) PutSiteElevation(elevation float64) error {
+	form := map[string]string{
+		"SiteElevation":       fmt.Sprintf("%g", elevation),
+		"ClientID":            fmt.Sprintf("%d", t.alpaca.ClientId),
+		"ClientTransactionID": fmt.Sprintf("%d", t.alpaca.GetNextTransactionId()),
+	}
+	err := t.alpaca.Put("telescope", t.Id, "siteelevation", form)
+	return err
+}
+
 func (t *Telescope) PutSiteLongitude(long float64) error {
 	form := map[string]string{
 		"SiteLongitude":       fmt.Sprintf("%g", long),
@@ -304,6 +437,33 @@ func (t *Telescope) PutAbortSlew() error {
 	return err
 }
 
+func (t *Telescope) PutPark() error {
+	form := map[string]string{
+		"ClientID":            fmt.Sprintf("%d", t.alpaca.ClientId),
+		"ClientTransactionID": fmt.Sprintf("%d", t.alpaca.GetNextTransactionId()),
+	}
+	err := t.alpaca.Put("telescope", t.Id, "park", form)
+	return err
+}
+
+func (t *Telescope) PutUnpark() error {
+	form := map[string]string{
+		"ClientID":            fmt.Sprintf("%d", t.alpaca.ClientId),
+		"ClientTransactionID": fmt.Sprintf("%d", t.alpaca.GetNextTransactionId()),
+	}
+	err := t.alpaca.Put("telescope", t.Id, "unpark", form)
+	return err
+}
+
+func (t *Telescope) PutFindHome() error {
+	form := map[string]string{
+		"ClientID":            fmt.Sprintf("%d", t.alpaca.ClientId),
+		"ClientTransactionID": fmt.Sprintf("%d", t.alpaca.GetNextTransactionId()),
+	}
+	err := t.alpaca.Put("telescope", t.Id, "findhome", form)
+	return err
+}
+
 func (t *Telescope) PutSlewToTargetAsync() error {
 	form := map[string]string{
 		"ClientID":            fmt.Sprintf("%d", t.alpaca.ClientId),
@@ -322,6 +482,58 @@ func (t *Telescope) PutSyncToTarget() error {
 	return err
 }
 
+// Duration is in milliseconds
+func (t *Telescope) PutPulseGuide(direction GuideDirection, duration int) error {
+	form := map[string]string{
+		"Direction":           fmt.Sprintf("%d", direction),
+		"Duration":            fmt.Sprintf("%d", duration),
+		"ClientID":            fmt.Sprintf("%d", t.alpaca.ClientId),
+		"ClientTransactionID": fmt.Sprintf("%d", t.alpaca.GetNextTransactionId()),
+	}
+	err := t.alpaca.Put("telescope", t.Id, "pulseguide", form)
+	return err
+}
+
+func (t *Telescope) PutGuideRateRightAscension(rate float64) error {
+	form := map[string]string{
+		"GuideRateRightAscension": fmt.Sprintf("%g", rate),
+		"ClientID":                fmt.Sprintf("%d", t.alpaca.ClientId),
+		"ClientTransactionID":     fmt.Sprintf("%d", t.alpaca.GetNextTransactionId()),
+	}
+	err := t.alpaca.Put("telescope", t.Id, "guideraterightascension", form)
+	return err
+}
+
+func (t *Telescope) PutGuideRateDeclination(rate float64) error {
+	form := map[string]string{
+		"GuideRateDeclination": fmt.Sprintf("%g", rate),
+		"ClientID":             fmt.Sprintf("%d", t.alpaca.ClientId),
+		"ClientTransactionID":  fmt.Sprintf("%d", t.alpaca.GetNextTransactionId()),
+	}
+	err := t.alpaca.Put("telescope", t.Id, "guideratedeclination", form)
+	return err
+}
+
+func (t *Telescope) PutTrackingRate(rate DriveRate) error {
+	form := map[string]string{
+		"TrackingRate":        fmt.Sprintf("%d", rate),
+		"ClientID":            fmt.Sprintf("%d", t.alpaca.ClientId),
+		"ClientTransactionID": fmt.Sprintf("%d", t.alpaca.GetNextTransactionId()),
+	}
+	err := t.alpaca.Put("telescope", t.Id, "trackingrate", form)
+	return err
+}
+
+func (t *Telescope) PutRightAscensionRate(rate float64) error {
+	form := map[string]string{
+		"RightAscensionRate":  fmt.Sprintf("%g", rate),
+		"ClientID":            fmt.Sprintf("%d", t.alpaca.ClientId),
+		"ClientTransactionID": fmt.Sprintf("%d", t.alpaca.GetNextTransactionId()),
+	}
+	err := t.alpaca.Put("telescope", t.Id, "rightascensionrate", form)
+	return err
+}
+
 func (t *Telescope) PutTracking(tracking TrackingMode) error {
 	enableTracking := tracking != NotTracking
 	form := map[string]string{
@@ -20,45 +20,49 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/synfinatic/alpacascope/alpaca"
+	"github.com/synfinatic/alpacascope/telescope"
 )
 
 // Our actual application config
 type AlpacaScopeConfig struct {
-	TelescopeProtocol   string `json:"TelescopeProtocol"`
-	TelescopeMount      string `json:"TelescopeMount"`
-	AutoTracking        bool   `json:"AutoTracking"`
-	ListenIP            string `json:"ListenIp"`
-	ListenPort          string `json:"ListenPort"`
-	AscomAuto           bool   `json:"AscomAuto"`
-	AutoConnectAttempts string `json:"AutoConnectAttempts"`
-	AutoStart           bool   `json:"AutoStart"`
-	AscomIP             string `json:"AscomIp"`
-	AscomPort           string `json:"AscomPort"`
-	AscomTelescope      string `json:"AscomTelescope"`
-	HighPrecisionLX200  bool   `json:"HighPrecisionLX200"`
-	isRunning           bool
-	Quit                chan bool      `json:"-"` // have to hide since public
-	EnableButtons       chan bool      `json:"-"`
-	store               *SettingsStore // platform specific
+	TelescopeProtocol   string            `json:"TelescopeProtocol"`
+	Options             map[string]string `json:"Options"` // telescope.ProtocolOption values by name
+	AutoTracking        bool              `json:"AutoTracking"`
+	ListenIP            string            `json:"ListenIp"`
+	ListenPort          string            `json:"ListenPort"`
+	AscomAuto           bool              `json:"AscomAuto"`
+	AutoConnectAttempts string            `json:"AutoConnectAttempts"`
+	AutoStart           bool              `json:"AutoStart"`
+	AscomIP             string            `json:"AscomIp"`
+	AscomPort           string            `json:"AscomPort"`
+	AscomTelescope      string            `json:"AscomTelescope"`
+	// replaced by Options, only loaded to convert older settings
+	TelescopeMount     string `json:"TelescopeMount,omitempty"`
+	HighPrecisionLX200 bool   `json:"HighPrecisionLX200,omitempty"`
+	isRunning          bool
+	Quit               chan bool      `json:"-"` // have to hide since public
+	EnableButtons      chan bool      `json:"-"`
+	store              *SettingsStore // platform specific
 }
 
 // Loads the config from our SettingsStore (if it exists),
 // otherwise will return our defaults.  Errors are informational
 // so you know why loading settings failed.
 func NewAlpacaScopeConfig() *AlpacaScopeConfig {
+	proto, _ := telescope.GetProtocol(telescope.DEFAULT_PROTOCOL)
 	config := &AlpacaScopeConfig{
-		TelescopeProtocol:   "NexStar",
-		TelescopeMount:      "Alt-Az",
-		HighPrecisionLX200:  false,
+		TelescopeProtocol:   proto.Label,
+		Options:             map[string]string{},
 		AutoTracking:        true,
 		AscomAuto:           true,
 		AutoConnectAttempts: "3",
 		AutoStart:           false,
 		ListenIP:            "All-Interfaces/0.0.0.0",
-		ListenPort:          "4030",
+		ListenPort:          strconv.Itoa(int(proto.DefaultPort)),
 		AscomIP:             "127.0.0.1",
 		AscomPort:           alpaca.DEFAULT_PORT_STR,
 		AscomTelescope:      "0",
@@ -66,6 +70,9 @@ func NewAlpacaScopeConfig() *AlpacaScopeConfig {
 		EnableButtons:       make(chan bool),
 		store:               NewSettingsStore(),
 	}
+	for _, o := range telescope.ProtocolOptions() {
+		config.Options[o.Name] = o.Default
+	}
 
 	return config
 }
@@ -77,6 +84,37 @@ func (a *AlpacaScopeConfig) Load() error {
 	// load config.  maybe it worked?  Don't care really....
 	err := s.GetSettings(a)
 	a.SetStore(s)
+
+	// protocol may have been removed or renamed
+	if _, perr := telescope.GetProtocol(a.TelescopeProtocol); perr != nil {
+		proto, _ := telescope.GetProtocol(telescope.DEFAULT_PROTOCOL)
+		a.TelescopeProtocol = proto.Label
+	}
+
+	// saved before we had protocol options
+	if a.TelescopeMount != "" {
+		switch a.TelescopeMount {
+		case "EQ North":
+			a.Options["mount-type"] = "eqn"
+		case "EQ South":
+			a.Options["mount-type"] = "eqs"
+		default:
+			a.Options["mount-type"] = "altaz"
+		}
+		a.Options["high-precision"] = strconv.FormatBool(a.HighPrecisionLX200)
+		a.TelescopeMount = ""
+		a.HighPrecisionLX200 = false
+	}
+
+	// options may have been added, removed or their choices changed
+	if a.Options == nil {
+		a.Options = map[string]string{}
+	}
+	for _, o := range telescope.ProtocolOptions() {
+		if value, ok := a.Options[o.Name]; !ok || o.Validate(value) != nil {
+			a.Options[o.Name] = o.Default
+		}
+	}
 	return err
 }
 
@@ -103,6 +141,15 @@ func (c *AlpacaScopeConfig) ListenIPAddress() string {
 	return ips[0]
 }
 
+// Returns the telescope.ProtocolConfig options for our settings
+func (c *AlpacaScopeConfig) ProtocolOptions() map[string]string {
+	options := map[string]string{}
+	for k, v := range c.Options {
+		options[k] = v
+	}
+	return options
+}
+
 func (c *AlpacaScopeConfig) IsRunning() bool {
 	return c.isRunning
 }
@@ -50,9 +50,8 @@ var sbox *StatusBox
 type Widgets struct {
 	form                *widget.Form
 	TelescopeProtocol   *widget.Select
-	TelescopeMount      *widget.Select
+	Options             []*OptionWidget // from the protocol registry
 	AutoTracking        *widget.Check
-	HighPrecisionLX200  *widget.Check
 	ListenIP            *widget.Select
 	ListenPort          *widget.Entry
 	AscomAuto           *widget.Check
@@ -91,8 +90,11 @@ func main() {
 
 	top := widget.NewForm(
 		widget.NewFormItem("Telescope Protocol", ourWidgets.TelescopeProtocol),
-		widget.NewFormItem("NexStar Mount Type", ourWidgets.TelescopeMount),
-		widget.NewFormItem("LX200 default to High Precision", ourWidgets.HighPrecisionLX200),
+	)
+	for _, item := range ourWidgets.OptionItems() {
+		top.AppendItem(item)
+	}
+	for _, item := range []*widget.FormItem{
 		widget.NewFormItem("Auto Tracking", ourWidgets.AutoTracking),
 		widget.NewFormItem("Listen IP", ourWidgets.ListenIP),
 		widget.NewFormItem("Listen Port", ourWidgets.ListenPort),
@@ -102,7 +104,9 @@ func main() {
 		widget.NewFormItem("ASCOM Telescope ID", ourWidgets.AscomTelescope),
 		widget.NewFormItem("Automatically Connect on Start", ourWidgets.AutoStart),
 		widget.NewFormItem("Connect Attempts", ourWidgets.AutoConnectAttempts),
-	)
+	} {
+		top.AppendItem(item)
+	}
 	ourWidgets.form = top
 
 	ourWidgets.Save = widget.NewButton("Save Settings", func() {
@@ -248,15 +252,7 @@ func (c *AlpacaScopeConfig) Run() {
 		return
 	}
 
-	var trackingMode alpaca.TrackingMode
-	switch c.TelescopeMount {
-	case "Alt-Az":
-		trackingMode = alpaca.AltAz
-	case "EQ North":
-		trackingMode = alpaca.EQNorth
-	case "EQ South":
-		trackingMode = alpaca.EQSouth
-	}
+	trackingMode := telescope.MountTypeTrackingMode(c.Options["mount-type"])
 
 	a := alpaca.NewAlpaca(clientid, shost, sport)
 	tid, _ := strconv.ParseUint(c.AscomTelescope, 10, 32)
@@ -305,17 +301,19 @@ func (c *AlpacaScopeConfig) Run() {
 		sbox.AddLine(fmt.Sprintf("Connected to telescope %s: %s", c.AscomTelescope, name))
 	}
 
-	var tscope telescope.TelescopeProtocol
-	switch c.TelescopeProtocol {
-	case "LX200":
-		minmax, err := scope.GetAxisRates(alpaca.AxisAzmRa)
-		if err != nil {
-			sbox.AddLine(fmt.Sprintf("Unable to query axis rates: %s", err.Error()))
-		}
-		tscope = telescope.NewLX200(c.AutoTracking, true, true, minmax, 100000)
-
-	case "NexStar":
-		tscope = telescope.NewNexStar(c.AutoTracking)
+	proto, err := telescope.GetProtocol(c.TelescopeProtocol)
+	if err != nil {
+		sbox.AddLine(err.Error())
+		tempQuit <- true
+		return
+	}
+	pconfig := telescope.NewProtocolConfig(c.AutoTracking)
+	pconfig.Options = c.ProtocolOptions()
+	tscope, err := proto.New(scope, pconfig)
+	if err != nil {
+		sbox.AddLine(fmt.Sprintf("Unable to start %s: %s", proto.Label, err.Error()))
+		tempQuit <- true
+		return
 	}
 
 	// Act like SkyFi
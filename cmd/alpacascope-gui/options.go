@@ -0,0 +1,98 @@
+package main
+
+/*
+ * AlpacaScope
+ * Copyright (c) 2020-2021 Aaron Turner  <synfinatic at gmail dot com>
+ *
+ * This program is free software: you can redistribute it
+ * and/or modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or with the authors permission any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"github.com/synfinatic/alpacascope/telescope"
+)
+
+/*
+ * Widget for a telescope.ProtocolOption: a check box for bools, a select
+ * for options with choices and an entry for everything else.
+ */
+type OptionWidget struct {
+	Option telescope.ProtocolOption
+	check  *widget.Check
+	sel    *widget.Select
+	entry  *widget.Entry
+}
+
+func NewOptionWidget(option telescope.ProtocolOption, config *AlpacaScopeConfig) *OptionWidget {
+	o := OptionWidget{Option: option}
+	value := config.Options[option.Name]
+	switch {
+	case option.Type == telescope.OptionBool:
+		o.check = widget.NewCheck("", func(enabled bool) {
+			if enabled {
+				config.Options[option.Name] = "true"
+			} else {
+				config.Options[option.Name] = "false"
+			}
+		})
+		o.check.Checked = value == "true"
+
+	case len(option.Choices) > 0:
+		o.sel = widget.NewSelect(option.Choices, func(val string) {
+			config.Options[option.Name] = val
+		})
+		o.sel.Selected = value
+
+	default:
+		o.entry = widget.NewEntry()
+		o.entry.SetText(value)
+		o.entry.Validator = option.Validate
+		o.entry.OnChanged = func(val string) {
+			if option.Validate(val) == nil {
+				config.Options[option.Name] = val
+			}
+		}
+	}
+	return &o
+}
+
+func (o *OptionWidget) Widget() fyne.CanvasObject {
+	switch {
+	case o.check != nil:
+		return o.check
+	case o.sel != nil:
+		return o.sel
+	}
+	return o.entry
+}
+
+func (o *OptionWidget) Set(value string) {
+	switch {
+	case o.check != nil:
+		o.check.SetChecked(value == "true")
+	case o.sel != nil:
+		o.sel.SetSelected(value)
+	default:
+		o.entry.SetText(value)
+	}
+}
+
+func (o *OptionWidget) Enable() {
+	o.Widget().(fyne.Disableable).Enable()
+}
+
+func (o *OptionWidget) Disable() {
+	o.Widget().(fyne.Disableable).Disable()
+}
@@ -19,6 +19,7 @@ package main
  */
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 
@@ -63,6 +64,10 @@ func (ss *SettingsStore) GetSettings(config *AlpacaScopeConfig) error {
 			default:
 				s.Field(i).SetBool(false)
 			}
+		case reflect.Map:
+			if err := json.Unmarshal([]byte(val), s.Field(i).Addr().Interface()); err != nil {
+				return fmt.Errorf("Unable to load %s: %s", field, err.Error())
+			}
 		default:
 			return fmt.Errorf("Unsupported type for %s: %v", field, kind)
 		}
@@ -100,6 +105,12 @@ func (ss *SettingsStore) SaveSettings(config *AlpacaScopeConfig) error {
 			} else {
 				err = key.SetStringValue(n, "false")
 			}
+		case reflect.Map:
+			jdata, jerr := json.Marshal(s.Field(i).Interface())
+			if jerr != nil {
+				return fmt.Errorf("Unable to save %s: %s", n, jerr.Error())
+			}
+			err = key.SetStringValue(n, string(jdata))
 
 		default:
 			return fmt.Errorf("Unsupported type for field %s: %v", n, t.Kind())
@@ -2,44 +2,41 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 
 	"fyne.io/fyne/v2/data/validation"
 	"fyne.io/fyne/v2/widget"
+	"github.com/synfinatic/alpacascope/telescope"
 	"github.com/synfinatic/alpacascope/utils"
 )
 
 func NewWidgets(config *AlpacaScopeConfig) *Widgets {
 	w := Widgets{}
 
-	// TelescopeMount
-	w.TelescopeMount = widget.NewSelect(
-		[]string{"Alt-Az", "EQ North", "EQ South"},
-		func(val string) {
-			config.TelescopeMount = val
-		},
-	)
-	w.TelescopeMount.Selected = config.TelescopeMount
+	// one widget per option from the protocol registry
+	for _, o := range telescope.ProtocolOptions() {
+		w.Options = append(w.Options, NewOptionWidget(o, config))
+	}
 
 	// Telescope Protocol
-	w.TelescopeProtocol = widget.NewSelect([]string{"NexStar", "LX200"},
-		func(proto string) {
-			config.TelescopeProtocol = proto
-			if proto == "NexStar" {
-				w.TelescopeMount.Enable()
-				w.HighPrecisionLX200.Disable()
-			} else {
-				// only NexStar supports the mountType
-				w.TelescopeMount.Disable()
-				// only LX200 supports high precision
-				w.HighPrecisionLX200.Enable()
+	w.TelescopeProtocol = widget.NewSelect(telescope.ProtocolLabels(),
+		func(label string) {
+			// switch to the new protocol's default port unless the user changed it
+			if old, err := telescope.GetProtocol(config.TelescopeProtocol); err == nil {
+				if proto, err := telescope.GetProtocol(label); err == nil &&
+					config.ListenPort == strconv.Itoa(int(old.DefaultPort)) {
+					config.ListenPort = strconv.Itoa(int(proto.DefaultPort))
+					if w.ListenPort != nil {
+						w.ListenPort.SetText(config.ListenPort)
+					}
+				}
 			}
+			config.TelescopeProtocol = label
+			w.setProtocolOptions(label)
 			w.form.Refresh()
 		},
 	)
 	w.TelescopeProtocol.Selected = config.TelescopeProtocol
-	if config.TelescopeProtocol == "LX200" {
-		w.TelescopeMount.Disable()
-	}
 
 	// AutoTracking
 	w.AutoTracking = widget.NewCheck("", func(enabled bool) {
@@ -48,18 +45,6 @@ func NewWidgets(config *AlpacaScopeConfig) *Widgets {
 	})
 	w.AutoTracking.Checked = config.AutoTracking
 
-	// HighPrecisionLX200
-	w.HighPrecisionLX200 = widget.NewCheck("", func(enabled bool) {
-		config.HighPrecisionLX200 = enabled
-		w.form.Refresh()
-	})
-	w.HighPrecisionLX200.Checked = config.HighPrecisionLX200
-	if config.TelescopeProtocol == "LX200" {
-		w.HighPrecisionLX200.Enable()
-	} else {
-		w.HighPrecisionLX200.Disable()
-	}
-
 	// ListenIp
 	ips, err := utils.GetLocalIPs()
 	if err != nil {
@@ -142,18 +127,40 @@ func NewWidgets(config *AlpacaScopeConfig) *Widgets {
 	})
 	w.AutoStart.Checked = config.AutoStart
 
+	w.setProtocolOptions(config.TelescopeProtocol)
+
 	// status field
 	w.Status = widget.NewTextGrid()
 	w.Status.SetText(STOPPED)
 	return &w
 }
 
+// Only enable the options supported by the protocol
+func (w *Widgets) setProtocolOptions(label string) {
+	proto, _ := telescope.GetProtocol(label)
+	for _, o := range w.Options {
+		if proto.HasOption(o.Option.Name) {
+			o.Enable()
+		} else {
+			o.Disable()
+		}
+	}
+}
+
+// Form items for all the protocol options
+func (w *Widgets) OptionItems() []*widget.FormItem {
+	items := []*widget.FormItem{}
+	for _, o := range w.Options {
+		items = append(items, widget.NewFormItem(o.Option.Label, o.Widget()))
+	}
+	return items
+}
+
 func (w *Widgets) Enable() {
 	w.Save.Enable()
 	w.Delete.Enable()
 	w.TelescopeProtocol.Enable()
-	w.TelescopeMount.Enable()
-	w.HighPrecisionLX200.Enable()
+	w.setProtocolOptions(w.TelescopeProtocol.Selected)
 	w.AutoTracking.Enable()
 	w.ListenIP.Enable()
 	w.ListenPort.Enable()
@@ -169,8 +176,9 @@ func (w *Widgets) Disable() {
 	w.Save.Disable()
 	w.Delete.Disable()
 	w.TelescopeProtocol.Disable()
-	w.TelescopeMount.Disable()
-	w.HighPrecisionLX200.Disable()
+	for _, o := range w.Options {
+		o.Disable()
+	}
 	w.AutoTracking.Disable()
 	w.ListenIP.Disable()
 	w.ListenPort.Disable()
@@ -184,8 +192,9 @@ func (w *Widgets) Disable() {
 
 func (w *Widgets) Set(config *AlpacaScopeConfig) {
 	w.TelescopeProtocol.SetSelected(config.TelescopeProtocol)
-	w.HighPrecisionLX200.SetChecked(config.HighPrecisionLX200)
-	w.TelescopeMount.SetSelected(config.TelescopeMount)
+	for _, o := range w.Options {
+		o.Set(config.Options[o.Option.Name])
+	}
 	w.AutoTracking.SetChecked(config.AutoTracking)
 	w.ListenIP.SetSelected(config.ListenIP)
 	w.ListenPort.SetText(config.ListenPort)
@@ -0,0 +1,314 @@
+package main
+
+/*
+ * AlpacaScope
+ * Copyright (c) 2020-2021 Aaron Turner  <synfinatic at gmail dot com>
+ *
+ * This program is free software: you can redistribute it
+ * and/or modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or with the authors permission any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/synfinatic/alpacascope/alpaca"
+	"github.com/synfinatic/alpacascope/serial"
+	"github.com/synfinatic/alpacascope/telescope"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Everything we need to know to run a single listener
+type ListenerConfig struct {
+	Mode        string
+	ListenIP    string
+	ListenPort  int32 // 0 for the protocol default
+	NoAutoTrack bool
+	TelescopeID uint32
+	AlpacaHost  string
+	AlpacaPort  int32
+	SerialPort  string // listen on a serial port instead of the network
+	Pty         bool   // create a PTY linked to SerialPort
+	Baud        int
+	Parity      string
+	Options     map[string]string // protocol options by name
+}
+
+// Returns the listener defined by the top level CLI flags
+func (cli *CLI) DefaultListener() ListenerConfig {
+	return ListenerConfig{
+		Mode:        cli.Mode,
+		ListenIP:    cli.ListenIP,
+		ListenPort:  cli.ListenPort,
+		NoAutoTrack: cli.NoAutoTrack,
+		TelescopeID: cli.TelescopeID,
+		AlpacaHost:  cli.AlpacaHost,
+		AlpacaPort:  cli.AlpacaPort,
+		Baud:        cli.Baud,
+		Parity:      cli.Parity,
+		Options:     cli.protocolFlags.Options(),
+	}
+}
+
+/*
+ * Parse a --listener spec of comma separated key=value pairs:
+ * mode=lx200,port=4031,telescope-id=1
+ *
+ * Any values not specified are taken from defaults.  Protocol options
+ * such as mount-type and high-precision come from the protocol registry.
+ */
+func ParseListener(spec string, defaults ListenerConfig) (ListenerConfig, error) {
+	lc := defaults
+	lc.Options = map[string]string{}
+	for k, v := range defaults.Options {
+		lc.Options[k] = v
+	}
+
+	for _, opt := range strings.Split(spec, ",") {
+		kv := strings.SplitN(strings.TrimSpace(opt), "=", 2)
+		key := kv[0]
+		value := ""
+		if len(kv) == 2 {
+			value = kv[1]
+		}
+
+		switch key {
+		case "mode":
+			if _, err := telescope.GetProtocol(value); err != nil {
+				return lc, fmt.Errorf("invalid mode: %s", value)
+			}
+			lc.Mode = value
+
+		case "ip":
+			lc.ListenIP = value
+
+		case "port":
+			port, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return lc, fmt.Errorf("invalid port: %s", value)
+			}
+			lc.ListenPort = int32(port)
+
+		case "no-auto-track":
+			lc.NoAutoTrack = value == "" || value == "true"
+
+		case "telescope-id":
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return lc, fmt.Errorf("invalid telescope-id: %s", value)
+			}
+			lc.TelescopeID = uint32(id)
+
+		case "alpaca-host":
+			lc.AlpacaHost = value
+
+		case "alpaca-port":
+			port, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return lc, fmt.Errorf("invalid alpaca-port: %s", value)
+			}
+			lc.AlpacaPort = int32(port)
+
+		case "serial":
+			lc.SerialPort = value
+			lc.Pty = false
+
+		case "pty":
+			lc.SerialPort = value
+			lc.Pty = true
+
+		case "baud":
+			baud, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return lc, fmt.Errorf("invalid baud: %s", value)
+			}
+			lc.Baud = int(baud)
+
+		case "parity":
+			lc.Parity = value
+
+		default:
+			if err := lc.setOption(key, value); err != nil {
+				return lc, err
+			}
+		}
+	}
+	return lc, nil
+}
+
+// Sets a protocol option.  Bool options may be given without a value.
+func (lc *ListenerConfig) setOption(key, value string) error {
+	for _, o := range telescope.ProtocolOptions() {
+		if o.Name != key {
+			continue
+		}
+		if o.Type == telescope.OptionBool && value == "" {
+			value = "true"
+		}
+		if err := o.Validate(value); err != nil {
+			return err
+		}
+		lc.Options[key] = value
+		return nil
+	}
+	return fmt.Errorf("unknown option: %s", key)
+}
+
+func (lc *ListenerConfig) MountType() string {
+	if mt, ok := lc.Options["mount-type"]; ok {
+		return mt
+	}
+	return "altaz"
+}
+
+func (lc *ListenerConfig) Address() string {
+	if lc.SerialPort != "" {
+		return lc.SerialPort
+	}
+	port := lc.ListenPort
+	if port == 0 {
+		if p, err := telescope.GetProtocol(lc.Mode); err == nil {
+			port = p.DefaultPort
+		}
+	}
+	return net.JoinHostPort(lc.ListenIP, strconv.Itoa(int(port)))
+}
+
+// Open our TCP socket or serial port for the listener
+func (lc *ListenerConfig) Listen(l *telescope.Listener) error {
+	if lc.SerialPort == "" {
+		return l.Listen()
+	}
+
+	config := serial.NewConfig(lc.SerialPort, lc.Pty)
+	config.Baud = lc.Baud
+	config.Parity = lc.Parity
+	ln, err := serial.Listen(config)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %s", lc.SerialPort, err.Error())
+	}
+	l.SetListener(ln)
+	return nil
+}
+
+// Returns the TelescopeProtocol for this listener
+func (lc *ListenerConfig) Protocol(scope *alpaca.Telescope) (telescope.TelescopeProtocol, error) {
+	p, err := telescope.GetProtocol(lc.Mode)
+	if err != nil {
+		return nil, err
+	}
+	config := telescope.NewProtocolConfig(!lc.NoAutoTrack)
+	config.Options = lc.Options
+	return p.New(scope, config)
+}
+
+/*
+ * Mounts tracks all the Alpaca servers & telescopes used by our listeners
+ * so that listeners pointing at the same mount share a connection.
+ */
+type Mounts struct {
+	clientID   uint32
+	servers    map[string]*alpaca.Alpaca
+	telescopes map[string]*alpaca.Telescope
+	autoHost   string
+	autoPort   int32
+}
+
+func NewMounts(clientID uint32) *Mounts {
+	return &Mounts{
+		clientID:   clientID,
+		servers:    map[string]*alpaca.Alpaca{},
+		telescopes: map[string]*alpaca.Telescope{},
+	}
+}
+
+// Returns the connected telescope for the listener.  Exits on error.
+func (m *Mounts) Get(lc ListenerConfig) *alpaca.Telescope {
+	host, port := lc.AlpacaHost, lc.AlpacaPort
+	if host == "auto" {
+		host, port = m.discover(port)
+	}
+
+	server := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	key := fmt.Sprintf("%s/%d/%s", server, lc.TelescopeID, lc.MountType())
+	if scope, ok := m.telescopes[key]; ok {
+		return scope
+	}
+
+	a, ok := m.servers[server]
+	if !ok {
+		a = alpaca.NewAlpaca(m.clientID, host, port)
+		m.servers[server] = a
+	}
+
+	scope := alpaca.NewTelescope(lc.TelescopeID, telescope.MountTypeTrackingMode(lc.MountType()), a)
+	connectTelescope(scope)
+	m.telescopes[key] = scope
+	return scope
+}
+
+// only auto discover our Alpaca server once
+func (m *Mounts) discover(port int32) (string, int32) {
+	var err error
+	if m.autoHost != "" {
+		return m.autoHost, m.autoPort
+	}
+
+	// first look locally since we can't rely on UDP broadcast to work locally on windows
+	m.autoPort = port
+	m.autoHost = alpaca.IsRunningLocal(port)
+	if m.autoHost == "" {
+		m.autoHost, m.autoPort, err = alpaca.DiscoverServer(3)
+		if err != nil {
+			log.Fatalf("Unable to auto discover Alpaca Remote Server.  Please specify --alpaca-host and --alpaca-port")
+		}
+	}
+	return m.autoHost, m.autoPort
+}
+
+func connectTelescope(scope *alpaca.Telescope) {
+	connected, err := scope.GetConnected()
+	if err != nil {
+		log.Fatalf("Unable to determine status of telescope: %s", err.Error())
+	}
+
+	if !connected {
+		err = scope.PutConnected(true)
+		if err != nil {
+			log.Fatalf("Unable to connect to telescope ID %d: %s", scope.Id, err.Error())
+		}
+		connected, err = scope.GetConnected()
+		if err != nil {
+			log.Fatalf("Unable to determine status of telescope: %s", err.Error())
+		}
+		if !connected {
+			log.Fatalf("Telescope is not connected to ASCOM Remote")
+		}
+	}
+
+	name, err := scope.GetName()
+	if err != nil {
+		log.Warnf("Unable to determine name of telescope: %s", err.Error())
+	} else {
+		log.Infof("Connected to telescope %d: %s", scope.Id, name)
+	}
+
+	actions, err := scope.GetSupportedActions()
+	if err != nil {
+		log.Fatalf("Unable to determine supportedactions of telescope: %s", err.Error())
+	}
+	log.Debugf("SupportedActions: %s", actions)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testDefaults() ListenerConfig {
+	return ListenerConfig{
+		Mode:        "nexstar",
+		ListenIP:    "0.0.0.0",
+		TelescopeID: 0,
+		AlpacaHost:  "localhost",
+		AlpacaPort:  11111,
+		Baud:        9600,
+		Parity:      "none",
+		Options: map[string]string{
+			"mount-type":     "altaz",
+			"high-precision": "false",
+		},
+	}
+}
+
+func TestParseListener(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Spec  string
+		Check func(t *testing.T, lc ListenerConfig)
+	}{
+		{"defaults", "mode=lx200", func(t *testing.T, lc ListenerConfig) {
+			assert.Equal(t, "lx200", lc.Mode)
+			assert.Equal(t, int32(0), lc.ListenPort)
+			assert.Equal(t, "localhost", lc.AlpacaHost)
+			assert.Equal(t, "altaz", lc.MountType())
+			assert.Equal(t, "0.0.0.0:4030", lc.Address())
+		}},
+		{"network", "mode=stellarium, ip=127.0.0.1, port=10001, telescope-id=2, alpaca-host=scope, alpaca-port=32323",
+			func(t *testing.T, lc ListenerConfig) {
+				assert.Equal(t, "127.0.0.1:10001", lc.Address())
+				assert.Equal(t, uint32(2), lc.TelescopeID)
+				assert.Equal(t, "scope", lc.AlpacaHost)
+				assert.Equal(t, int32(32323), lc.AlpacaPort)
+			}},
+		{"bare bools", "mode=lx200,no-auto-track,high-precision", func(t *testing.T, lc ListenerConfig) {
+			assert.True(t, lc.NoAutoTrack)
+			assert.Equal(t, "true", lc.Options["high-precision"])
+		}},
+		{"bool values", "no-auto-track=false,high-precision=false", func(t *testing.T, lc ListenerConfig) {
+			assert.False(t, lc.NoAutoTrack)
+			assert.Equal(t, "false", lc.Options["high-precision"])
+		}},
+		{"protocol options", "mount-type=eqn,focuser-id=1", func(t *testing.T, lc ListenerConfig) {
+			assert.Equal(t, "eqn", lc.MountType())
+			assert.Equal(t, "1", lc.Options["focuser-id"])
+		}},
+		{"serial", "serial=/dev/ttyUSB0,baud=19200,parity=even", func(t *testing.T, lc ListenerConfig) {
+			assert.Equal(t, "/dev/ttyUSB0", lc.SerialPort)
+			assert.False(t, lc.Pty)
+			assert.Equal(t, 19200, lc.Baud)
+			assert.Equal(t, "even", lc.Parity)
+			assert.Equal(t, "/dev/ttyUSB0", lc.Address())
+		}},
+		{"pty", "pty=/tmp/alpacascope", func(t *testing.T, lc ListenerConfig) {
+			assert.Equal(t, "/tmp/alpacascope", lc.SerialPort)
+			assert.True(t, lc.Pty)
+			assert.Equal(t, 9600, lc.Baud)
+		}},
+		{"last serial or pty wins", "pty=/tmp/alpacascope,serial=/dev/ttyS0", func(t *testing.T, lc ListenerConfig) {
+			assert.Equal(t, "/dev/ttyS0", lc.SerialPort)
+			assert.False(t, lc.Pty)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			defaults := testDefaults()
+			lc, err := ParseListener(test.Spec, defaults)
+			assert.NoError(t, err)
+			test.Check(t, lc)
+			// the defaults are never modified
+			assert.Equal(t, testDefaults(), defaults)
+		})
+	}
+}
+
+func TestParseListenerErrors(t *testing.T) {
+	tests := map[string]string{ // spec: error
+		"mode=meade":            "invalid mode: meade",
+		"port=":                 "invalid port: ",
+		"port=lx200":            "invalid port: lx200",
+		"port=99999999999":      "invalid port: 99999999999",
+		"alpaca-port=-":         "invalid alpaca-port: -",
+		"telescope-id=-1":       "invalid telescope-id: -1",
+		"baud=fast":             "invalid baud: fast",
+		"focus":                 "unknown option: focus",
+		"mode=lx200,speed=9600": "unknown option: speed",
+		"mount-type=dob":        "",
+		"mount-type":            "",
+		"focuser-id=one":        "",
+		"high-precision=maybe":  "",
+	}
+	for spec, msg := range tests {
+		_, err := ParseListener(spec, testDefaults())
+		if assert.Error(t, err, spec) && msg != "" {
+			assert.Equal(t, msg, err.Error(), spec)
+		}
+	}
+}
+
+func TestSetOption(t *testing.T) {
+	lc := testDefaults()
+	lc.Options = map[string]string{}
+
+	assert.NoError(t, lc.setOption("high-precision", ""))
+	assert.Equal(t, "true", lc.Options["high-precision"])
+	assert.NoError(t, lc.setOption("high-precision", "false"))
+	assert.Equal(t, "false", lc.Options["high-precision"])
+	assert.NoError(t, lc.setOption("lx200-profile", "onstep"))
+	assert.Equal(t, "onstep", lc.Options["lx200-profile"])
+
+	// invalid values don't change the option
+	assert.Error(t, lc.setOption("high-precision", "yes please"))
+	assert.Equal(t, "false", lc.Options["high-precision"])
+	assert.Error(t, lc.setOption("lx200-profile", ""))
+	assert.Equal(t, "onstep", lc.Options["lx200-profile"])
+
+	assert.EqualError(t, lc.setOption("no-such-option", "1"), "unknown option: no-such-option")
+	assert.NotContains(t, lc.Options, "no-such-option")
+}
+
+// Fake Alpaca server which counts the connection checks for each telescope
+type mockServer struct {
+	lock     sync.Mutex
+	connects map[string]int // by TelescopeID
+}
+
+func (m *mockServer) Connects(id string) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.connects[id]
+}
+
+func newMockServer(t *testing.T) (string, int32, *mockServer) {
+	m := &mockServer{connects: map[string]int{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Split(r.URL.Path, "/")
+		api := path[len(path)-1]
+		resp := map[string]interface{}{"ErrorNumber": 0, "ErrorMessage": ""}
+		switch api {
+		case "connected":
+			m.lock.Lock()
+			m.connects[path[len(path)-2]]++
+			m.lock.Unlock()
+			resp["Value"] = true
+		case "name":
+			resp["Value"] = "Mock Telescope"
+		case "supportedactions":
+			resp["Value"] = []string{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	p, _ := strconv.ParseInt(port, 10, 32)
+	return host, int32(p), m
+}
+
+func TestMountsGet(t *testing.T) {
+	host, port, server := newMockServer(t)
+	defaults := testDefaults()
+	defaults.AlpacaHost = host
+	defaults.AlpacaPort = port
+	mounts := NewMounts(1)
+
+	nexstar, err := ParseListener("mode=nexstar", defaults)
+	assert.NoError(t, err)
+	lx200, err := ParseListener("mode=lx200,port=4031", defaults)
+	assert.NoError(t, err)
+	eqn, err := ParseListener("mode=lx200,port=4032,mount-type=eqn", defaults)
+	assert.NoError(t, err)
+	second, err := ParseListener("mode=stellarium,telescope-id=1", defaults)
+	assert.NoError(t, err)
+
+	// listeners for the same telescope & mount type share it
+	scope := mounts.Get(nexstar)
+	assert.Same(t, scope, mounts.Get(lx200))
+	assert.Equal(t, 1, server.Connects("0"))
+
+	// a different mount type or telescope gets its own
+	assert.NotSame(t, scope, mounts.Get(eqn))
+	other := mounts.Get(second)
+	assert.NotSame(t, scope, other)
+	assert.Equal(t, uint32(1), other.Id)
+	assert.Equal(t, 2, server.Connects("0"))
+	assert.Equal(t, 1, server.Connects("1"))
+
+	// but they all share the same Alpaca server
+	assert.Len(t, mounts.servers, 1)
+	assert.Len(t, mounts.telescopes, 3)
+}
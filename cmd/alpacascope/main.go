@@ -21,13 +21,13 @@ package main
 import (
 	"fmt"
 	"math/rand"
-	"net"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/alecthomas/kong"
 	colorable "github.com/mattn/go-colorable"
-	"github.com/synfinatic/alpacascope/alpaca"
 	"github.com/synfinatic/alpacascope/skyfi"
 	"github.com/synfinatic/alpacascope/telescope"
 	"github.com/synfinatic/alpacascope/utils"
@@ -42,28 +42,25 @@ var Tag = "NO-TAG"
 var CommitID = "unknown"
 var Delta = ""
 
-type TeleComms int
-
-const (
-	NexStar TeleComms = iota
-	LX200
-)
-
 type CLI struct {
-	AlpacaHost    string `default:"auto" short:"H" help:"FQDN or IP address of Alpaca server"`
-	AlpacaPort    int32  `default:"11111" short:"P" help:"TCP port of the Alpaca server"`
-	ClientID      uint32 `default:"0" short:"c" help:"Override Alpaca ClientID used for debugging"`
-	TelescopeID   uint32 `default:"0" short:"t" help:"Alpaca TelescopeID"`
-	ListenIP      string `default:"0.0.0.0" help:"IP to listen on for clients"`
-	ListenPort    int32  `default:"4030" help:"TCP port to listen on for clients (default: 4030)"`
-	SerialPort    string `default:"/dev/alpacascope" short:"p" help:"Specify serial port to listen for connections"`
-	Serial        bool   `short:"s" help:"Listen on serial port instead of network"`
-	Mode          string `short:"m" default:"nexstar" enum:"nexstar,lx200" help:"Comms mode: [nexstar|lx200]"`
-	MountType     string `default:"altaz" enum:"altaz,eqn,eqs" help:"Mount type: [altaz|eqn|eqs]"`
-	HighPrecision bool   `help:"Default to High Precision in LX200 mode"`
-	NoAutoTrack   bool   `help:"Do not enable auto-track"`
-	Debug         bool   `help:"Enable debug logging"`
-	Version       bool   `help:"Print version and exit"`
+	AlpacaHost  string   `default:"auto" short:"H" help:"FQDN or IP address of Alpaca server"`
+	AlpacaPort  int32    `default:"11111" short:"P" help:"TCP port of the Alpaca server"`
+	ClientID    uint32   `default:"0" short:"c" help:"Override Alpaca ClientID used for debugging"`
+	TelescopeID uint32   `default:"0" short:"t" help:"Alpaca TelescopeID"`
+	ListenIP    string   `default:"0.0.0.0" help:"IP to listen on for clients"`
+	ListenPort  int32    `default:"0" help:"TCP port to listen on for clients (default: protocol specific)"`
+	SerialPort  string   `default:"/dev/alpacascope" short:"p" help:"Specify serial port to listen for connections"`
+	Serial      bool     `short:"s" help:"Listen on serial port instead of network"`
+	Pty         bool     `help:"Create a pseudo-terminal linked to --serial-port instead of network (Linux only)"`
+	Baud        int      `default:"9600" help:"Serial port baud rate"`
+	Parity      string   `default:"none" enum:"none,even,odd" help:"Serial port parity: [none|even|odd]"`
+	Mode        string   `short:"m" default:"${default_mode}" enum:"${modes}" help:"Comms mode: [${modes_help}]"`
+	NoAutoTrack bool     `help:"Do not enable auto-track"`
+	Listener    []string `short:"l" sep:"none" help:"Add a listener: mode=MODE,port=PORT[,ip=IP]${listener_options}[,no-auto-track][,telescope-id=ID][,alpaca-host=HOST][,alpaca-port=PORT][,serial=DEV][,pty=PATH][,baud=BAUD][,parity=PARITY].  May be repeated"`
+	Debug       bool     `help:"Enable debug logging"`
+	Version     bool     `help:"Print version and exit"`
+
+	protocolFlags *ProtocolFlags // generated from the protocol registry
 }
 
 type RunContext struct {
@@ -72,19 +69,26 @@ type RunContext struct {
 }
 
 func main() {
-	cli := CLI{}
+	cli := CLI{
+		protocolFlags: NewProtocolFlags(telescope.ProtocolOptions()),
+	}
 	parser := kong.Must(
 		&cli,
+		kong.Embed(cli.protocolFlags.Struct()),
 		kong.Name("alpacascope"),
 		kong.Description("Alpaca to Telescope Gateway"),
 		kong.UsageOnError(),
-		kong.Vars{},
+		kong.Vars{
+			"default_mode": telescope.DEFAULT_PROTOCOL,
+			"modes":        strings.Join(telescope.ProtocolNames(), ","),
+			"modes_help":   strings.Join(telescope.ProtocolNames(), "|"),
+
+			"listener_options": ListenerOptionsHelp(telescope.ProtocolOptions()),
+		},
 	)
 	_, err := parser.Parse(os.Args[1:])
 	parser.FatalIfErrorf(err)
-
-	var mode TeleComms
-	var trackingMode alpaca.TrackingMode
+	parser.FatalIfErrorf(cli.protocolFlags.Validate())
 
 	if cli.ClientID == 0 {
 		cli.ClientID = rand.Uint32() // nolint:gosec
@@ -124,105 +128,49 @@ func main() {
 		os.Exit(0)
 	}
 
-	switch cli.Mode {
-	case "nexstar":
-		mode = NexStar
-	case "lx200":
-		mode = LX200
-	}
-	switch cli.MountType {
-	case "altaz":
-		trackingMode = alpaca.AltAz
-	case "eqn":
-		trackingMode = alpaca.EQNorth
-	case "eqs":
-		trackingMode = alpaca.EQSouth
-	}
-
-	var ln net.Listener
-	if !cli.Serial {
-		listen := fmt.Sprintf("%s:%d", cli.ListenIP, cli.ListenPort)
-		ln, err = net.Listen("tcp", listen)
-		if err != nil {
-			log.Fatalf("Error listening on %s: %s", listen, err.Error())
+	listeners := []ListenerConfig{}
+	if len(cli.Listener) == 0 {
+		lc := cli.DefaultListener()
+		if cli.Serial || cli.Pty {
+			lc.SerialPort = cli.SerialPort
+			lc.Pty = cli.Pty
 		}
-	} else { // nolint:staticcheck
-		// do the serial port needful.
-	}
-	defer ln.Close()
-
-	if cli.AlpacaHost == "auto" {
-		// first look locally since we can't rely on UDP broadcast to work locally on windows
-		cli.AlpacaHost = alpaca.IsRunningLocal(cli.AlpacaPort)
-		if cli.AlpacaHost == "" {
-			cli.AlpacaHost, cli.AlpacaPort, err = alpaca.DiscoverServer(3)
+		listeners = append(listeners, lc)
+	} else {
+		for _, spec := range cli.Listener {
+			lc, err := ParseListener(spec, cli.DefaultListener())
 			if err != nil {
-				log.Fatalf("Unable to auto discover Alpaca Remote Server.  Please specify --alpaca-host and --alpaca-port")
+				log.Fatalf("Invalid --listener %s: %s", spec, err.Error())
 			}
+			listeners = append(listeners, lc)
 		}
 	}
 
 	// Act like a SkyFi for discovery
 	go skyfi.ReplyDiscover()
 
-	a := alpaca.NewAlpaca(cli.ClientID, cli.AlpacaHost, cli.AlpacaPort)
-	scope := alpaca.NewTelescope(cli.TelescopeID, trackingMode, a)
-
-	connected, err := scope.GetConnected()
-	if err != nil {
-		log.Fatalf("Unable to determine status of telescope: %s", err.Error())
-	}
-
-	if !connected {
-		err = scope.PutConnected(true)
+	mounts := NewMounts(cli.ClientID)
+	running := []*telescope.Listener{}
+	for _, lc := range listeners {
+		scope := mounts.Get(lc)
+		proto, err := lc.Protocol(scope)
 		if err != nil {
-			log.Fatalf("Unable to connect to telescope ID %d: %s", cli.TelescopeID, err.Error())
+			log.Fatalf("Unable to create %s listener: %s", lc.Mode, err.Error())
 		}
-		connected, err = scope.GetConnected()
-		if err != nil {
-			log.Fatalf("Unable to determine status of telescope: %s", err.Error())
-		}
-		if !connected {
-			log.Fatalf("Telescope is not connected to ASCOM Remote")
+		l := telescope.NewListener(lc.Mode, lc.Address(), proto, scope)
+		if err = lc.Listen(l); err != nil {
+			log.Fatalf("%s", err.Error())
 		}
+		running = append(running, l)
+		go l.Serve()
 	}
 
-	name, err := scope.GetName()
-	if err != nil {
-		log.Warnf("Unable to determine name of telescope: %s", err.Error())
-	} else {
-		log.Infof("Connected to telescope %d: %s", cli.TelescopeID, name)
-	}
-
-	actions, err := scope.GetSupportedActions()
-	if err != nil {
-		log.Fatalf("Unable to determine supportedactions of telescope: %s", err.Error())
-	}
-	log.Debugf("SupportedActions: %s", actions)
-
-	var tscope telescope.TelescopeProtocol
-	switch mode {
-	case LX200:
-		minmax, err := scope.GetAxisRates(alpaca.AxisAzmRa)
-		if err != nil {
-			log.Errorf("Unable to query axis rates: %s", err.Error())
-		}
-		tscope = telescope.NewLX200(!cli.NoAutoTrack, cli.HighPrecision, true, minmax, 100000)
-	case NexStar:
-		tscope = telescope.NewNexStar(!cli.NoAutoTrack)
-	default:
-		log.Fatalf("Unsupported mode value: %d", mode)
-	}
-
-	log.Infof("Waiting for %s clients on %s:%d\n", cli.Mode, cli.ListenIP, cli.ListenPort)
-
-	for {
-		conn, err := ln.Accept()
-		if err != nil {
-			log.Warnf("Error calling Accept(): %s", err.Error())
-			continue
-		}
-
-		tscope.HandleConnection(conn, scope)
+	// listeners run until we are told to exit.  Close them so PTY symlinks are removed
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	log.Infof("Shutting down...")
+	for _, l := range running {
+		l.Close() // nolint:errcheck
 	}
 }
@@ -0,0 +1,149 @@
+package main
+
+/*
+ * AlpacaScope
+ * Copyright (c) 2020-2021 Aaron Turner  <synfinatic at gmail dot com>
+ *
+ * This program is free software: you can redistribute it
+ * and/or modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or with the authors permission any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+/*
+ * The protocol options in the telescope registry become CLI flags by
+ * building a struct at runtime which kong.Embed() can parse, so adding an
+ * option to a protocol doesn't require any changes here.
+ */
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/synfinatic/alpacascope/telescope"
+)
+
+type ProtocolFlags struct {
+	options []telescope.ProtocolOption
+	value   reflect.Value // pointer to our generated struct
+}
+
+func NewProtocolFlags(options []telescope.ProtocolOption) *ProtocolFlags {
+	fields := []reflect.StructField{}
+	for _, o := range options {
+		fields = append(fields, reflect.StructField{
+			Name: optionFieldName(o.Name),
+			Type: optionFieldType(o),
+			Tag:  optionTag(o),
+		})
+	}
+	return &ProtocolFlags{
+		options: options,
+		value:   reflect.New(reflect.StructOf(fields)),
+	}
+}
+
+// Returns the struct pointer for kong.Embed()
+func (f *ProtocolFlags) Struct() interface{} {
+	return f.value.Interface()
+}
+
+// Returns the value of every option by name
+func (f *ProtocolFlags) Options() map[string]string {
+	values := map[string]string{}
+	s := f.value.Elem()
+	for i, o := range f.options {
+		field := s.Field(i)
+		switch field.Kind() {
+		case reflect.Bool:
+			values[o.Name] = strconv.FormatBool(field.Bool())
+		default:
+			values[o.Name] = field.String()
+		}
+	}
+	return values
+}
+
+// Validates all of the option values
+func (f *ProtocolFlags) Validate() error {
+	values := f.Options()
+	for _, o := range f.options {
+		if err := o.Validate(values[o.Name]); err != nil {
+			return fmt.Errorf("--%s: %s", o.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// Returns the --listener help for the protocol options
+func ListenerOptionsHelp(options []telescope.ProtocolOption) string {
+	help := []string{}
+	for _, o := range options {
+		if o.Type == telescope.OptionBool {
+			help = append(help, fmt.Sprintf("[,%s]", o.Name))
+		} else {
+			help = append(help, fmt.Sprintf("[,%s=%s]", o.Name, optionPlaceholder(o.Name)))
+		}
+	}
+	return strings.Join(help, "")
+}
+
+// high-precision => HighPrecision
+func optionFieldName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_'
+	})
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[0:1]) + w[1:]
+	}
+	return strings.Join(words, "")
+}
+
+// focuser-id => FOCUSER_ID
+func optionPlaceholder(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+func optionFieldType(o telescope.ProtocolOption) reflect.Type {
+	if o.Type == telescope.OptionBool {
+		return reflect.TypeOf(false)
+	}
+	// OptionInt is a string so that it may be unset
+	return reflect.TypeOf("")
+}
+
+func optionTag(o telescope.ProtocolOption) reflect.StructTag {
+	help := o.Help
+	if len(o.Choices) > 0 {
+		help = fmt.Sprintf("%s: [%s]", help, strings.Join(o.Choices, "|"))
+	}
+	tags := []string{
+		fmt.Sprintf("name:%q", o.Name),
+		fmt.Sprintf("help:%q", help),
+		`group:"Protocol Options"`,
+	}
+	if o.Default != "" {
+		tags = append(tags, fmt.Sprintf("default:%q", o.Default))
+	}
+	if len(o.Choices) > 0 {
+		tags = append(tags, fmt.Sprintf("enum:%q", strings.Join(o.Choices, ",")))
+	}
+	if o.Type == telescope.OptionBool {
+		if o.Default == "true" {
+			tags = append(tags, `negatable:""`)
+		}
+	} else {
+		tags = append(tags, fmt.Sprintf("placeholder:%q", optionPlaceholder(o.Name)))
+	}
+	return reflect.StructTag(strings.Join(tags, " "))
+}
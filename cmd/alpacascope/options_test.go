@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/alecthomas/kong"
+	"github.com/stretchr/testify/assert"
+	"github.com/synfinatic/alpacascope/telescope"
+)
+
+func TestProtocolFlags(t *testing.T) {
+	flags := NewProtocolFlags(telescope.ProtocolOptions())
+	parser, err := kong.New(&struct{}{}, kong.Embed(flags.Struct()), kong.Exit(func(int) { t.Fatal("exit") }))
+	assert.NoError(t, err)
+
+	// every registered option is a flag with its default
+	_, err = parser.Parse([]string{})
+	assert.NoError(t, err)
+	options := flags.Options()
+	for _, o := range telescope.ProtocolOptions() {
+		assert.Contains(t, options, o.Name)
+		if o.Type == telescope.OptionBool {
+			assert.Equal(t, "false", options[o.Name], o.Name)
+		} else {
+			assert.Equal(t, o.Default, options[o.Name], o.Name)
+		}
+	}
+	assert.NoError(t, flags.Validate())
+
+	_, err = parser.Parse([]string{"--high-precision", "--lx200-profile", "onstep", "--focuser-id", "2"})
+	assert.NoError(t, err)
+	options = flags.Options()
+	assert.Equal(t, "true", options["high-precision"])
+	assert.Equal(t, "onstep", options["lx200-profile"])
+	assert.Equal(t, "2", options["focuser-id"])
+
+	// choices are enforced by kong, everything else by Validate()
+	_, err = parser.Parse([]string{"--mount-type", "dob"})
+	assert.Error(t, err)
+	_, err = parser.Parse([]string{"--settle-time", "soon"})
+	assert.NoError(t, err)
+	assert.Error(t, flags.Validate())
+}
+
+func TestListenerOptionsHelp(t *testing.T) {
+	help := ListenerOptionsHelp([]telescope.ProtocolOption{
+		{Name: "high-precision", Type: telescope.OptionBool},
+		{Name: "focuser-id", Type: telescope.OptionInt},
+	})
+	assert.Equal(t, "[,high-precision][,focuser-id=FOCUSER_ID]", help)
+}
@@ -0,0 +1,212 @@
+package serial
+
+/*
+ * AlpacaScope
+ * Copyright (c) 2020-2021 Aaron Turner  <synfinatic at gmail dot com>
+ *
+ * This program is free software: you can redistribute it
+ * and/or modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or with the authors permission any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+/*
+ * Serial ports and pseudo-terminals wrapped up as a net.Listener so that
+ * serial-only planetarium software can talk to the same TelescopeProtocol
+ * handlers we use for TCP clients.
+ *
+ * Unlike TCP, there is only ever one "client" on a serial port, so Accept()
+ * returns a single connection and blocks until that connection is closed
+ * before handing out the next one.
+ */
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_BAUD   = 9600
+	DEFAULT_PARITY = "none"
+)
+
+type Config struct {
+	Device   string // serial device to open, or symlink to create for PTY
+	Pty      bool   // create a pseudo-terminal instead of opening Device
+	Baud     int
+	Parity   string // none, even, odd
+	StopBits int    // 1 or 2
+}
+
+func NewConfig(device string, pty bool) Config {
+	return Config{
+		Device:   device,
+		Pty:      pty,
+		Baud:     DEFAULT_BAUD,
+		Parity:   DEFAULT_PARITY,
+		StopBits: 1,
+	}
+}
+
+func (c Config) Validate() error {
+	switch c.Parity {
+	case "none", "even", "odd":
+	default:
+		return fmt.Errorf("invalid parity: %s", c.Parity)
+	}
+	if c.StopBits != 1 && c.StopBits != 2 {
+		return fmt.Errorf("invalid stop bits: %d", c.StopBits)
+	}
+	return nil
+}
+
+type Addr struct {
+	Device string
+}
+
+func (a Addr) Network() string {
+	return "serial"
+}
+
+func (a Addr) String() string {
+	return a.Device
+}
+
+// platform specific serial port or PTY
+type port interface {
+	file() (*os.File, error) // file for a new client session
+	release(*os.File) error  // client session is over
+	close() error
+	name() string
+}
+
+type Listener struct {
+	config    Config
+	port      port // platform specific
+	available chan bool
+	done      chan bool
+	closeOnce sync.Once
+}
+
+// Open the serial port or create the PTY
+func Listen(config Config) (*Listener, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	p, err := openPort(config)
+	if err != nil {
+		return nil, err
+	}
+
+	l := Listener{
+		config:    config,
+		port:      p,
+		available: make(chan bool, 1),
+		done:      make(chan bool),
+	}
+	l.available <- true
+	return &l, nil
+}
+
+// Blocks until the previous connection has been closed
+func (l *Listener) Accept() (net.Conn, error) {
+	// select picks at random when both are ready, so check done first
+	select {
+	case <-l.done:
+		return nil, net.ErrClosed
+	default:
+	}
+
+	select {
+	case <-l.done:
+		return nil, net.ErrClosed
+	case <-l.available:
+	}
+
+	f, err := l.port.file()
+	if err != nil {
+		l.available <- true
+		return nil, err
+	}
+	return &Conn{
+		file:     f,
+		addr:     Addr{Device: l.config.Device},
+		listener: l,
+	}, nil
+}
+
+func (l *Listener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.done)
+		err = l.port.close()
+	})
+	return err
+}
+
+func (l *Listener) Addr() net.Addr {
+	return Addr{Device: l.config.Device}
+}
+
+// Name of the serial device actually in use
+func (l *Listener) Device() string {
+	return l.port.name()
+}
+
+// Conn is a single client session on our serial port
+type Conn struct {
+	file      *os.File
+	addr      Addr
+	listener  *Listener
+	closeOnce sync.Once
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	return c.file.Read(b)
+}
+
+func (c *Conn) Write(b []byte) (int, error) {
+	return c.file.Write(b)
+}
+
+// Close lets the Listener hand out the serial port again
+func (c *Conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.listener.port.release(c.file)
+		c.listener.available <- true
+	})
+	return err
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.addr
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.file.SetDeadline(t)
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.file.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.file.SetWriteDeadline(t)
+}
@@ -0,0 +1,237 @@
+//go:build linux
+// +build linux
+
+package serial
+
+/*
+ * AlpacaScope
+ * Copyright (c) 2020-2021 Aaron Turner  <synfinatic at gmail dot com>
+ *
+ * This program is free software: you can redistribute it
+ * and/or modify it under the terms of the GNU General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or with the authors permission any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+var baudRates = map[int]uint32{
+	1200:   unix.B1200,
+	2400:   unix.B2400,
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+}
+
+func openPort(config Config) (port, error) {
+	if _, ok := baudRates[config.Baud]; !ok {
+		return nil, fmt.Errorf("unsupported baud rate: %d", config.Baud)
+	}
+	if config.Pty {
+		return newPty(config)
+	}
+	return &devicePort{config: config}, nil
+}
+
+// Put the tty into raw mode with our baud, parity & stop bits
+func setTermios(fd int, config Config) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return err
+	}
+
+	speed := baudRates[config.Baud]
+
+	// same as cfmakeraw()
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP |
+		unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.INPCK
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CBAUD
+	t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL | speed
+
+	switch config.Parity {
+	case "even":
+		t.Cflag |= unix.PARENB
+		t.Iflag |= unix.INPCK
+	case "odd":
+		t.Cflag |= unix.PARENB | unix.PARODD
+		t.Iflag |= unix.INPCK
+	}
+
+	if config.StopBits == 2 {
+		t.Cflag |= unix.CSTOPB
+	}
+
+	t.Ispeed = speed
+	t.Ospeed = speed
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+
+	return unix.IoctlSetTermios(fd, unix.TCSETS, t)
+}
+
+/*
+ * A real serial device.  We (re)open it for every session so that
+ * unplugging a USB serial adapter doesn't require a restart.
+ */
+type devicePort struct {
+	config Config
+}
+
+func (d *devicePort) file() (*os.File, error) {
+	// O_NONBLOCK so that reads go through the poller and support deadlines
+	f, err := os.OpenFile(d.config.Device, os.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = setTermios(int(f.Fd()), d.config); err != nil { // nolint:gosec
+		f.Close()
+		return nil, fmt.Errorf("unable to configure %s: %s", d.config.Device, err.Error())
+	}
+	return f, nil
+}
+
+func (d *devicePort) release(f *os.File) error {
+	return f.Close()
+}
+
+func (d *devicePort) close() error {
+	return nil
+}
+
+func (d *devicePort) name() string {
+	return d.config.Device
+}
+
+/*
+ * A pseudo-terminal with a symlink (/dev/alpacascope) pointing at the
+ * slave side for other software to open.  We keep our own copy of the
+ * slave open so reading the master blocks instead of returning EIO when
+ * the client software closes the port.
+ */
+type ptyPort struct {
+	config Config
+	master *os.File
+	slave  *os.File
+	path   string // /dev/pts/X
+	lock   sync.Mutex
+	closed bool
+}
+
+func newPty(config Config) (*ptyPort, error) {
+	fd, err := unix.Open("/dev/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open /dev/ptmx: %s", err.Error())
+	}
+	master := os.NewFile(uintptr(fd), "/dev/ptmx")
+
+	// unlockpt()
+	if err = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		master.Close()
+		return nil, fmt.Errorf("unable to unlock pty: %s", err.Error())
+	}
+
+	// ptsname()
+	ptn, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		master.Close()
+		return nil, fmt.Errorf("unable to get pty name: %s", err.Error())
+	}
+	path := fmt.Sprintf("/dev/pts/%d", ptn)
+
+	slave, err := os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, fmt.Errorf("unable to open %s: %s", path, err.Error())
+	}
+
+	p := ptyPort{
+		config: config,
+		master: master,
+		slave:  slave,
+		path:   path,
+	}
+
+	if err = setTermios(int(slave.Fd()), config); err != nil { // nolint:gosec
+		p.close() // nolint:errcheck
+		return nil, fmt.Errorf("unable to configure %s: %s", path, err.Error())
+	}
+
+	if config.Device != "" {
+		if err = p.link(); err != nil {
+			p.close() // nolint:errcheck
+			return nil, err
+		}
+	}
+	log.Infof("Created pseudo-terminal %s", path)
+	return &p, nil
+}
+
+// create our symlink, replacing any stale one left behind
+func (p *ptyPort) link() error {
+	if fi, err := os.Lstat(p.config.Device); err == nil {
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("refusing to replace %s: not a symlink", p.config.Device)
+		}
+		if err = os.Remove(p.config.Device); err != nil {
+			return fmt.Errorf("unable to remove stale %s: %s", p.config.Device, err.Error())
+		}
+	}
+
+	if err := os.Symlink(p.path, p.config.Device); err != nil {
+		return fmt.Errorf("unable to create %s: %s", p.config.Device, err.Error())
+	}
+	return nil
+}
+
+// The master stays open for the life of the listener
+func (p *ptyPort) file() (*os.File, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return nil, os.ErrClosed
+	}
+	return p.master, nil
+}
+
+func (p *ptyPort) release(f *os.File) error {
+	return nil
+}
+
+func (p *ptyPort) close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.closed = true
+	if p.config.Device != "" {
+		if target, err := os.Readlink(p.config.Device); err == nil && target == p.path {
+			os.Remove(p.config.Device) // nolint:errcheck
+		}
+	}
+	p.slave.Close()
+	return p.master.Close()
+}
+
+func (p *ptyPort) name() string {
+	return p.path
+}
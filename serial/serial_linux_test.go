@@ -0,0 +1,76 @@
+//go:build linux
+// +build linux
+
+package serial
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigValidate(t *testing.T) {
+	c := NewConfig("/dev/ttyS0", false)
+	assert.NoError(t, c.Validate())
+
+	c.Parity = "mark"
+	assert.Error(t, c.Validate())
+
+	c = NewConfig("/dev/ttyS0", false)
+	c.StopBits = 3
+	assert.Error(t, c.Validate())
+
+	c = NewConfig("/dev/ttyS0", false)
+	c.Baud = 1234
+	_, err := Listen(c)
+	assert.Error(t, err)
+}
+
+func TestPty(t *testing.T) {
+	link := path.Join(t.TempDir(), "alpacascope")
+	l, err := Listen(NewConfig(link, true))
+	if err != nil {
+		t.Skipf("unable to create pty: %s", err.Error())
+	}
+
+	target, err := os.Readlink(link)
+	assert.NoError(t, err)
+	assert.Equal(t, l.Device(), target)
+
+	client, err := os.OpenFile(link, os.O_RDWR, 0)
+	assert.NoError(t, err)
+	defer client.Close()
+
+	conn, err := l.Accept()
+	assert.NoError(t, err)
+
+	// client -> us
+	_, err = client.Write([]byte(":GR#"))
+	assert.NoError(t, err)
+	buf := make([]byte, 16)
+	n, err := conn.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, ":GR#", string(buf[:n]))
+
+	// us -> client
+	_, err = conn.Write([]byte("12:30:00#"))
+	assert.NoError(t, err)
+	n, err = client.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "12:30:00#", string(buf[:n]))
+
+	// closing the session makes the pty available again
+	assert.NoError(t, conn.Close())
+	conn, err = l.Accept()
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Close())
+
+	// closing the listener removes our symlink
+	assert.NoError(t, l.Close())
+	_, err = os.Lstat(link)
+	assert.True(t, os.IsNotExist(err))
+	_, err = l.Accept()
+	assert.Error(t, err)
+}
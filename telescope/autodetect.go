@@ -0,0 +1,200 @@
+package telescope
+
+/*
+ * Auto-detect which telescope protocol a client is speaking by sniffing the
+ * first bytes it sends and then hand the connection off to the matching
+ * TelescopeProtocol.  This allows a single port to serve any client.
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/synfinatic/alpacascope/alpaca"
+)
+
+const (
+	// Stellarium clients are silent until they send a goto, so if we
+	// don't hear anything by then, use AutoDetect.Fallback
+	AUTODETECT_TIMEOUT = 2 * time.Second
+)
+
+type ProtocolType int
+
+const (
+	ProtocolUnknown ProtocolType = iota
+	ProtocolLX200
+	ProtocolNexStar
+	ProtocolStellarium
+	ProtocolINDI
+)
+
+func (p ProtocolType) String() string {
+	switch p {
+	case ProtocolLX200:
+		return "LX200"
+	case ProtocolNexStar:
+		return "NexStar"
+	case ProtocolStellarium:
+		return "Stellarium"
+	case ProtocolINDI:
+		return "INDI"
+	}
+	return "Unknown"
+}
+
+// INDI clients always start by asking for the list of properties
+var indiGetProperties = []byte("<getProperties")
+
+// Returns the protocol a client is speaking based on the first bytes it sent
+func DetectProtocol(buf []byte) ProtocolType {
+	if len(buf) == 0 {
+		return ProtocolUnknown
+	}
+
+	switch buf[0] {
+	case 0x06, ':':
+		return ProtocolLX200
+
+	case 'K', 'V', 'e', 'E':
+		return ProtocolNexStar
+
+	case STELLARIUM_GOTO_LEN:
+		// LENGTH is a little endian uint16 followed by a TYPE of 0
+		if len(buf) >= 4 && bytes.Equal(buf[1:4], []byte{0, 0, 0}) {
+			return ProtocolStellarium
+		}
+
+	case '<':
+		if bytes.HasPrefix(buf, indiGetProperties) {
+			return ProtocolINDI
+		}
+	}
+	return ProtocolUnknown
+}
+
+// Returns the protocol for the auto-fallback option
+func ParseProtocolType(name string) (ProtocolType, error) {
+	for _, p := range []ProtocolType{ProtocolLX200, ProtocolNexStar, ProtocolStellarium} {
+		if strings.EqualFold(name, p.String()) {
+			return p, nil
+		}
+	}
+	return ProtocolUnknown, fmt.Errorf("invalid protocol: %s", name)
+}
+
+type AutoDetect struct {
+	LX200      *LX200
+	NexStar    *NexStar
+	Stellarium *Stellarium
+	Timeout    time.Duration
+	Fallback   ProtocolType // for clients which are silent until Timeout
+}
+
+func NewAutoDetect(lx200 *LX200, nexstar *NexStar, stellarium *Stellarium, fallback ProtocolType) *AutoDetect {
+	return &AutoDetect{
+		LX200:      lx200,
+		NexStar:    nexstar,
+		Stellarium: stellarium,
+		Timeout:    AUTODETECT_TIMEOUT,
+		Fallback:   fallback,
+	}
+}
+
+var autoFallbackOption = ProtocolOption{
+	Name:    "auto-fallback",
+	Label:   "Auto Protocol for Silent Clients",
+	Help:    "Protocol for auto mode clients which send nothing when they connect",
+	Type:    OptionString,
+	Default: DEFAULT_PROTOCOL,
+	Choices: []string{"nexstar", "lx200", "stellarium"},
+}
+
+func init() {
+	RegisterProtocol(Protocol{
+		Name:        "auto",
+		Label:       "Auto",
+		Description: "Auto-detect LX200, NexStar or Stellarium per connection",
+		DefaultPort: 4030,
+		Order:       50,
+		Options:     []ProtocolOption{autoFallbackOption, mountTypeOption, nexstarProfileOption, slewRatesOption, highPrecisionOption, lx200ProfileOption, focuserIDOption, settleTimeOption, passthroughOption, sitesFileOption},
+		Factory: func(scope *alpaca.Telescope, config ProtocolConfig) (TelescopeProtocol, error) {
+			fallback, _ := ParseProtocolType(config.String("auto-fallback")) // already validated
+			return NewAutoDetect(
+				newLX200(scope, config),
+				newNexStar(config),
+				NewStellarium(config.AutoTrack),
+				fallback,
+			), nil
+		},
+	})
+}
+
+func (a *AutoDetect) HandleConnection(conn net.Conn, t *alpaca.Telescope) {
+	sniffed := newSniffConn(conn)
+	proto := sniffed.detect(a.Timeout, a.Fallback)
+	log.Debugf("Detected %s client from %s", proto.String(), conn.RemoteAddr().String())
+
+	switch proto {
+	case ProtocolLX200:
+		a.LX200.HandleConnection(sniffed, t)
+	case ProtocolNexStar:
+		a.NexStar.HandleConnection(sniffed, t)
+	case ProtocolStellarium:
+		a.Stellarium.HandleConnection(sniffed, t)
+	case ProtocolINDI:
+		log.Errorf("INDI clients are not supported: %s", conn.RemoteAddr().String())
+		conn.Close()
+	default:
+		log.Errorf("Unable to detect protocol for client: %s", conn.RemoteAddr().String())
+		conn.Close()
+	}
+}
+
+/*
+ * sniffConn lets us peek at the first bytes from the client and then
+ * replays them to the real protocol handler
+ */
+type sniffConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func newSniffConn(conn net.Conn) *sniffConn {
+	return &sniffConn{
+		Conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+func (s *sniffConn) Read(b []byte) (int, error) {
+	return s.reader.Read(b)
+}
+
+// Returns the detected protocol or fallback if the client is silent
+func (s *sniffConn) detect(timeout time.Duration, fallback ProtocolType) ProtocolType {
+	_ = s.SetReadDeadline(time.Now().Add(timeout))
+	defer s.SetReadDeadline(time.Time{}) // nolint:errcheck
+
+	buf, err := s.reader.Peek(1)
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return fallback
+		}
+		return ProtocolUnknown
+	}
+
+	// some protocols need a few more bytes to be sure
+	switch buf[0] {
+	case STELLARIUM_GOTO_LEN:
+		buf, _ = s.reader.Peek(4)
+	case '<':
+		buf, _ = s.reader.Peek(len(indiGetProperties))
+	}
+	return DetectProtocol(buf)
+}
@@ -0,0 +1,85 @@
+package telescope
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectProtocol(t *testing.T) {
+	tests := map[ProtocolType][][]byte{
+		ProtocolLX200: {
+			{0x06},
+			[]byte(":GR#"),
+			[]byte(":Sr12:30:00#:Sd+45*00:00#"),
+		},
+		ProtocolNexStar: {
+			{'K', 'x'},
+			[]byte("V"),
+			[]byte("e"),
+			[]byte("E"),
+		},
+		ProtocolStellarium: {
+			{20, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0x80, 0, 0, 0, 0x20},
+		},
+		ProtocolINDI: {
+			[]byte("<getProperties version='1.7'/>"),
+		},
+		ProtocolUnknown: {
+			{},
+			{20},
+			{20, 0, 1, 0},
+			[]byte("<newNumberVector"),
+			[]byte("GET / HTTP/1.1"),
+		},
+	}
+
+	for check, bufs := range tests {
+		for _, buf := range bufs {
+			assert.Equal(t, check, DetectProtocol(buf), "%v", buf)
+		}
+	}
+}
+
+func TestSniffConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		_, _ = client.Write([]byte(":GR#"))
+	}()
+
+	sniffed := newSniffConn(server)
+	assert.Equal(t, ProtocolLX200, sniffed.detect(time.Second, ProtocolStellarium))
+
+	// sniffed bytes must be replayed to the protocol handler
+	buf := make([]byte, 4)
+	n, err := sniffed.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, ":GR#", string(buf[:n]))
+}
+
+func TestSniffConnTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// silent clients use the fallback protocol
+	sniffed := newSniffConn(server)
+	assert.Equal(t, ProtocolNexStar, sniffed.detect(10*time.Millisecond, ProtocolNexStar))
+	assert.Equal(t, ProtocolStellarium, sniffed.detect(10*time.Millisecond, ProtocolStellarium))
+}
+
+func TestParseProtocolType(t *testing.T) {
+	p, err := ParseProtocolType("stellarium")
+	assert.NoError(t, err)
+	assert.Equal(t, ProtocolStellarium, p)
+	p, err = ParseProtocolType("lx200")
+	assert.NoError(t, err)
+	assert.Equal(t, ProtocolLX200, p)
+	_, err = ParseProtocolType("indi")
+	assert.Error(t, err)
+}
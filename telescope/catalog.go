@@ -0,0 +1,330 @@
+package telescope
+
+/*
+ * Embedded object catalog for the LX200 object library: Messier, Caldwell,
+ * a subset of bright NGC objects, named stars and the planets & Moon.
+ *
+ * The NGC catalog includes every Messier and Caldwell object with an NGC
+ * number.  Star numbers are our own, except that the planets are stars
+ * 901 (Mercury) to 907 (Neptune) and the Moon is star 910.  The Sun is
+ * deliberately not included so nobody slews to it by accident.
+ */
+
+import (
+	"embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//go:embed catalog/*.csv
+var catalogFiles embed.FS
+
+const (
+	CATALOG_MESSIER  = "M"
+	CATALOG_CALDWELL = "C"
+	CATALOG_NGC      = "NGC"
+	CATALOG_STAR     = "STAR"
+)
+
+type ObjectType string
+
+const (
+	ObjectGalaxy           ObjectType = "G"
+	ObjectOpenCluster      ObjectType = "OC"
+	ObjectGlobularCluster  ObjectType = "GC"
+	ObjectPlanetaryNebula  ObjectType = "PN"
+	ObjectDiffuseNebula    ObjectType = "DN"
+	ObjectDarkNebula       ObjectType = "DK"
+	ObjectSupernovaRemnant ObjectType = "SNR"
+	ObjectDoubleStar       ObjectType = "DS"
+	ObjectAsterism         ObjectType = "AST"
+	ObjectStarCloud        ObjectType = "SC"
+	ObjectStar             ObjectType = "STAR"
+	ObjectPlanet           ObjectType = "PLANET"
+	ObjectMoon             ObjectType = "MOON"
+)
+
+// Meade style abbreviations for :LI# and :CM#
+var objectTypeNames = map[ObjectType]string{
+	ObjectGalaxy:           "EX GAL",
+	ObjectOpenCluster:      "OPEN CL",
+	ObjectGlobularCluster:  "GLOB CL",
+	ObjectPlanetaryNebula:  "PLNTRY",
+	ObjectDiffuseNebula:    "DIFF NEB",
+	ObjectDarkNebula:       "DARK NEB",
+	ObjectSupernovaRemnant: "SN REM",
+	ObjectDoubleStar:       "DBL STAR",
+	ObjectAsterism:         "ASTERISM",
+	ObjectStarCloud:        "STAR CLD",
+	ObjectStar:             "STAR",
+	ObjectPlanet:           "PLANET",
+	ObjectMoon:             "MOON",
+}
+
+type CatalogObject struct {
+	Catalog string // CATALOG_*
+	Number  int
+	NGC     int // 0 if none
+	Type    ObjectType
+	Name    string  // common name, may be empty
+	RA      float64 // J2000 hours
+	Dec     float64 // J2000 degrees
+	Mag     float64
+	Size    float64 // arcmin, 0 if unknown
+	body    *SolarSystemBody
+}
+
+var (
+	catalogOnce sync.Once
+	catalogs    map[string][]CatalogObject // sorted by Number
+)
+
+var solarSystemStars = map[int]SolarSystemBody{
+	901: Mercury,
+	902: Venus,
+	903: Mars,
+	904: Jupiter,
+	905: Saturn,
+	906: Uranus,
+	907: Neptune,
+	910: Moon,
+}
+
+var solarSystemNames = map[SolarSystemBody]string{
+	Mercury: "Mercury",
+	Venus:   "Venus",
+	Mars:    "Mars",
+	Jupiter: "Jupiter",
+	Saturn:  "Saturn",
+	Uranus:  "Uranus",
+	Neptune: "Neptune",
+	Moon:    "Moon",
+}
+
+func loadCatalogs() {
+	catalogs = map[string][]CatalogObject{}
+	for c, file := range map[string]string{CATALOG_MESSIER: "messier.csv", CATALOG_CALDWELL: "caldwell.csv"} {
+		objects, err := readDeepSkyCatalog(c, file)
+		if err != nil {
+			panic(err)
+		}
+		catalogs[c] = objects
+	}
+
+	ngc, err := readDeepSkyCatalog(CATALOG_NGC, "ngc.csv")
+	if err != nil {
+		panic(err)
+	}
+	seen := map[int]bool{}
+	for _, o := range ngc {
+		seen[o.Number] = true
+	}
+	for _, c := range []string{CATALOG_MESSIER, CATALOG_CALDWELL} {
+		for _, o := range catalogs[c] {
+			if o.NGC != 0 && !seen[o.NGC] {
+				seen[o.NGC] = true
+				o.Catalog = CATALOG_NGC
+				o.Number = o.NGC
+				ngc = append(ngc, o)
+			}
+		}
+	}
+	catalogs[CATALOG_NGC] = ngc
+
+	stars, err := readStarCatalog("stars.csv")
+	if err != nil {
+		panic(err)
+	}
+	for number, body := range solarSystemStars {
+		b := body
+		objectType := ObjectPlanet
+		if body == Moon {
+			objectType = ObjectMoon
+		}
+		stars = append(stars, CatalogObject{
+			Catalog: CATALOG_STAR,
+			Number:  number,
+			Type:    objectType,
+			Name:    solarSystemNames[body],
+			body:    &b,
+		})
+	}
+	catalogs[CATALOG_STAR] = stars
+
+	for _, objects := range catalogs {
+		sort.Slice(objects, func(i, j int) bool {
+			return objects[i].Number < objects[j].Number
+		})
+	}
+}
+
+// Reads the CSV file skipping comments
+func readCatalogFile(file string, fields int) ([][]string, error) {
+	f, err := catalogFiles.Open("catalog/" + file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = fields
+	records := [][]string{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err.Error())
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// number,ngc,type,ra,dec,mag,size,name
+func readDeepSkyCatalog(catalog, file string) ([]CatalogObject, error) {
+	records, err := readCatalogFile(file, 8)
+	if err != nil {
+		return nil, err
+	}
+	objects := []CatalogObject{}
+	for _, r := range records {
+		o := CatalogObject{
+			Catalog: catalog,
+			Type:    ObjectType(r[2]),
+			Name:    r[7],
+		}
+		if _, ok := objectTypeNames[o.Type]; !ok {
+			return nil, fmt.Errorf("%s: invalid type for %s", file, r[0])
+		}
+		errs := []error{}
+		o.Number, err = strconv.Atoi(r[0])
+		errs = append(errs, err)
+		o.NGC, err = strconv.Atoi(r[1])
+		errs = append(errs, err)
+		o.RA, err = ParseSexagesimalRange(r[3], 0.0, 24.0)
+		errs = append(errs, err)
+		o.Dec, err = ParseSexagesimalRange(r[4], -90.0, 90.0)
+		errs = append(errs, err)
+		o.Mag, err = strconv.ParseFloat(r[5], 64)
+		errs = append(errs, err)
+		o.Size, err = strconv.ParseFloat(r[6], 64)
+		errs = append(errs, err)
+		for _, err := range errs {
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid entry %s: %s", file, r[0], err.Error())
+			}
+		}
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
+// number,ra,dec,mag,name
+func readStarCatalog(file string) ([]CatalogObject, error) {
+	records, err := readCatalogFile(file, 5)
+	if err != nil {
+		return nil, err
+	}
+	objects := []CatalogObject{}
+	for _, r := range records {
+		o := CatalogObject{
+			Catalog: CATALOG_STAR,
+			Type:    ObjectStar,
+			Name:    r[4],
+		}
+		errs := []error{}
+		o.Number, err = strconv.Atoi(r[0])
+		errs = append(errs, err)
+		o.RA, err = ParseSexagesimalRange(r[1], 0.0, 24.0)
+		errs = append(errs, err)
+		o.Dec, err = ParseSexagesimalRange(r[2], -90.0, 90.0)
+		errs = append(errs, err)
+		o.Mag, err = strconv.ParseFloat(r[3], 64)
+		errs = append(errs, err)
+		for _, err := range errs {
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid entry %s: %s", file, r[0], err.Error())
+			}
+		}
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
+// Returns all the objects in the catalog sorted by number
+func CatalogObjects(catalog string) []CatalogObject {
+	catalogOnce.Do(loadCatalogs)
+	return catalogs[catalog]
+}
+
+// Returns the object from the catalog
+func LookupObject(catalog string, number int) (CatalogObject, error) {
+	objects := CatalogObjects(catalog)
+	i := sort.Search(len(objects), func(i int) bool {
+		return objects[i].Number >= number
+	})
+	if i < len(objects) && objects[i].Number == number {
+		return objects[i], nil
+	}
+	return CatalogObject{}, fmt.Errorf("unknown object: %s %d", catalog, number)
+}
+
+// Short name of the object: M42, NGC 1976, Sirius
+func (o CatalogObject) Label() string {
+	switch o.Catalog {
+	case CATALOG_MESSIER, CATALOG_CALDWELL:
+		return fmt.Sprintf("%s%d", o.Catalog, o.Number)
+	case CATALOG_NGC:
+		return fmt.Sprintf("NGC %d", o.Number)
+	}
+	return o.Name
+}
+
+// Describes the object like a Meade hand controller
+func (o CatalogObject) Description() string {
+	desc := o.Label()
+	if o.Name != "" && o.Name != desc {
+		desc += " " + o.Name
+	}
+	desc += " " + objectTypeNames[o.Type]
+	if o.body != nil {
+		return desc
+	}
+	desc += fmt.Sprintf(" MAG %.1f", o.Mag)
+	if o.Size > 0.0 {
+		desc += fmt.Sprintf(" SZ%5.1f'", o.Size)
+	}
+	return desc
+}
+
+// Returns the J2000 RA (hours) & Dec (degrees) of the object at the given time
+func (o CatalogObject) Position(t time.Time) (float64, float64) {
+	if o.body != nil {
+		return o.body.Position(t)
+	}
+	return o.RA, o.Dec
+}
+
+// Returns the class of object for the :Gy# filter (G, P, D, C or O)
+func (o CatalogObject) Class() byte {
+	switch o.Type {
+	case ObjectGalaxy:
+		return 'G'
+	case ObjectPlanetaryNebula:
+		return 'P'
+	case ObjectDiffuseNebula, ObjectDarkNebula, ObjectSupernovaRemnant:
+		return 'D'
+	case ObjectGlobularCluster:
+		return 'C'
+	case ObjectOpenCluster, ObjectAsterism, ObjectStarCloud:
+		return 'O'
+	}
+	return 0
+}
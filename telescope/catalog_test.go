@@ -0,0 +1,83 @@
+package telescope
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogs(t *testing.T) {
+	assert.Len(t, CatalogObjects(CATALOG_MESSIER), 110)
+	assert.Len(t, CatalogObjects(CATALOG_CALDWELL), 109)
+	assert.Len(t, CatalogObjects(CATALOG_STAR), 83+8) // planets & Moon
+
+	for _, catalog := range []string{CATALOG_MESSIER, CATALOG_CALDWELL, CATALOG_NGC, CATALOG_STAR} {
+		objects := CatalogObjects(catalog)
+		for i := 1; i < len(objects); i++ {
+			assert.Less(t, objects[i-1].Number, objects[i].Number, catalog)
+		}
+	}
+}
+
+func TestLookupObject(t *testing.T) {
+	m42, err := LookupObject(CATALOG_MESSIER, 42)
+	assert.NoError(t, err)
+	assert.Equal(t, "M42", m42.Label())
+	assert.Equal(t, ObjectDiffuseNebula, m42.Type)
+	assert.InDelta(t, 5.59, m42.RA, 0.001)
+	assert.InDelta(t, -5.45, m42.Dec, 0.001)
+	assert.Equal(t, "M42 Orion Nebula DIFF NEB MAG 4.0 SZ 85.0'", m42.Description())
+
+	// Messier & Caldwell objects are also in the NGC catalog
+	ngc, err := LookupObject(CATALOG_NGC, 1976)
+	assert.NoError(t, err)
+	assert.Equal(t, "NGC 1976", ngc.Label())
+	assert.Equal(t, m42.RA, ngc.RA)
+
+	c14, err := LookupObject(CATALOG_CALDWELL, 14)
+	assert.NoError(t, err)
+	assert.Equal(t, "C14 Double Cluster OPEN CL MAG 4.3 SZ 60.0'", c14.Description())
+
+	sirius, err := LookupObject(CATALOG_STAR, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Sirius", sirius.Label())
+	assert.Equal(t, "Sirius STAR MAG -1.5", sirius.Description())
+
+	jupiter, err := LookupObject(CATALOG_STAR, 904)
+	assert.NoError(t, err)
+	assert.Equal(t, "Jupiter PLANET", jupiter.Description())
+
+	_, err = LookupObject(CATALOG_MESSIER, 111)
+	assert.Error(t, err)
+	_, err = LookupObject(CATALOG_STAR, 900) // no Sun
+	assert.Error(t, err)
+	_, err = LookupObject("IC", 1)
+	assert.Error(t, err)
+}
+
+func TestSolarSystemPosition(t *testing.T) {
+	// J2000 positions at opposition
+	tests := []struct {
+		Body SolarSystemBody
+		Date string
+		RA   float64
+		Dec  float64
+	}{
+		{Jupiter, "2021-08-20T00:00:00Z", 21.97, -13.66},
+		{Saturn, "2021-08-02T00:00:00Z", 20.84, -18.50},
+		{Mars, "2020-10-13T00:00:00Z", 1.36, 5.33},
+	}
+	for _, test := range tests {
+		now, _ := time.Parse(time.RFC3339, test.Date)
+		ra, dec := test.Body.Position(now)
+		assert.InDelta(t, test.RA, ra, 0.02, test.Date)
+		assert.InDelta(t, test.Dec, dec, 0.2, test.Date)
+	}
+
+	// the Sun is on the equator at the March equinox, so the full Moon is
+	// roughly opposite the Earth/Sun line
+	now, _ := time.Parse(time.RFC3339, "2021-03-28T18:48:00Z")
+	ra, _ := Moon.Position(now)
+	assert.InDelta(t, 12.5, ra, 0.25)
+}
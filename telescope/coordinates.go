@@ -19,31 +19,12 @@ type Coordinates struct {
 
 // Returns RA as HMS struct
 func (c *Coordinates) RAToHMS() HMS {
-	h := int(c.RA)
-	remainder := c.RA - float64(h)
-	m := int(remainder * 60.0)
-	// remove the minutes from the hours and leave fractions of minute
-	fracMinute := c.RA - float64(h) - float64(m)/60.0
-	s := 60.0 * fracMinute
-	return HMS{
-		Hours:   h,
-		Minutes: m,
-		Seconds: s,
-	}
+	return NewHMSHours(c.RA)
 }
 
 func (c *Coordinates) DecToDegrees() DMS {
-	degrees := int(c.Dec)
-	remainder := c.Dec - float64(degrees)
-	minutes := int(remainder * 60.0)
-	// remove the minutes from the degrees and leave fractions of minute
-	fracMinute := c.Dec - float64(degrees) - float64(minutes)/60.0
-	seconds := 60.0 * fracMinute
-	return DMS{
-		Degrees: degrees,
-		Minutes: minutes,
-		Seconds: seconds,
-	}
+	// NewDMSDegrees handles negative values without the minutes going negative
+	return NewDMSDegrees(c.Dec)
 }
 
 /*
@@ -81,6 +62,32 @@ func GetAz(hourangle float64, dec float64, latitude float64) float64 {
 	}
 }
 
+/*
+ * Returns the RA (hours) & Dec (degrees) for an Alt/Az (degrees, Az measured
+ * from North through East) given the latitude (degrees) and local sidereal
+ * time (hours)
+ */
+func AltAzToRaDec(alt float64, az float64, latitude float64, lst float64) (float64, float64) {
+	ralt := Degs2rads(alt)
+	raz := Degs2rads(az)
+	rlat := Degs2rads(latitude)
+
+	sinDec := math.Sin(ralt)*math.Sin(rlat) + math.Cos(ralt)*math.Cos(rlat)*math.Cos(raz)
+	rdec := math.Asin(sinDec)
+
+	// hour angle, west of the meridian is positive
+	ha := math.Atan2(
+		-math.Sin(raz)*math.Cos(ralt),
+		math.Sin(ralt)*math.Cos(rlat)-math.Cos(ralt)*math.Sin(rlat)*math.Cos(raz),
+	)
+
+	ra := math.Mod(lst-Rads2degs(ha)/15.0, 24.0)
+	if ra < 0.0 {
+		ra += 24.0
+	}
+	return ra, Rads2degs(rdec)
+}
+
 // Convert RA + LocalSiderialTime to Hour Angle (RA/LST should be in degrees)
 func RAToHourAngle(ra HMS, lst float64) float64 {
 	var ha = lst - ra.ToDegrees()
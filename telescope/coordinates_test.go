@@ -76,3 +76,25 @@ func TestGetAltAz(t *testing.T) {
 	assert.Equal(t, az.ToFloat(), altaz.Az)
 }
 */
+
+func TestAltAzToRaDec(t *testing.T) {
+	// reverse of TestGetAlt & TestGetAz
+	ra, dec := AltAzToRaDec(49.169127488469556, 269.1463277297406, 52.5, 10.0)
+	assert.InDelta(t, 10.0-54.382617/15.0, ra, 1e-6)
+	assert.InDelta(t, 36.466667, dec, 1e-6)
+
+	// zenith is our latitude at the LST
+	ra, dec = AltAzToRaDec(90.0, 0.0, 40.0, 23.5)
+	assert.InDelta(t, 23.5, ra, 1e-6)
+	assert.InDelta(t, 40.0, dec, 1e-6)
+
+	// due south on the meridian
+	ra, dec = AltAzToRaDec(30.0, 180.0, 40.0, 0.25)
+	assert.InDelta(t, 0.25, ra, 1e-6)
+	assert.InDelta(t, -20.0, dec, 1e-6)
+
+	// due east on the horizon is on the equator, 6 hours before transit
+	ra, dec = AltAzToRaDec(0.0, 90.0, 40.0, 3.0)
+	assert.InDelta(t, 9.0, ra, 1e-6)
+	assert.InDelta(t, 0.0, dec, 1e-6)
+}
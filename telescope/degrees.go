@@ -21,62 +21,30 @@ type DMS struct {
 }
 
 func NewDMS(degrees int, minutes int, seconds float64) DMS {
-	dms := DMS{
-		Degrees:       degrees,
-		Minutes:       minutes,
-		Seconds:       seconds,
-		Float:         0.0,
-		FloatPositive: 0.0,
-	}
-	dms.Float = dms.toFloat()
-	if degrees < 0 {
-		dms.FloatPositive = dms.Float + 360
-	} else {
-		dms.FloatPositive = dms.Float
-	}
-	return dms
+	return newDMS(degrees, minutes, seconds, joinSexagesimal(degrees, minutes, seconds))
 }
 
 func NewDMSDegrees(degrees float64) DMS {
-	var deg, min, sec float64
-	if degrees > 0 {
-		deg = math.Floor(degrees)
-		min = math.Floor((degrees - deg) * 60.0)
-		sec = (degrees - deg - (min / 60.0)) * 3600.0
-	} else {
-		deg = math.Ceil(degrees)
-		min = math.Abs(math.Ceil((degrees - deg) * 60.0))
-		sec = math.Abs((degrees - deg + (min / 60.0)) * 3600.0)
-	}
-	dms := DMS{
-		Degrees:       int(deg),
-		Minutes:       int(min),
-		Seconds:       sec,
-		Float:         degrees,
-		FloatPositive: degrees,
-	}
-	if degrees < 0 {
-		dms.FloatPositive += 360
-	}
-	return dms
+	d, m, s := splitSexagesimal(degrees)
+	return newDMS(d, m, s, degrees)
 }
 
+// Sometimes we express things in degrees & minutes.frac_min
 func NewDMSShort(degrees int, minutes float64) DMS {
-	min := math.Floor(minutes)
-	sec := (minutes - min) * 60.0
+	whole := math.Floor(minutes)
+	return NewDMS(degrees, int(whole), (minutes-whole)*60.0)
+}
 
+func newDMS(degrees int, minutes int, seconds float64, value float64) DMS {
 	dms := DMS{
 		Degrees:       degrees,
-		Minutes:       int(min),
-		Seconds:       sec,
-		Float:         0.0,
-		FloatPositive: 0.0,
+		Minutes:       minutes,
+		Seconds:       seconds,
+		Float:         value,
+		FloatPositive: value,
 	}
-	dms.Float = dms.toFloat()
-	if degrees < 0 {
-		dms.FloatPositive = dms.Float + 360
-	} else {
-		dms.FloatPositive = dms.Float
+	if value < 0 {
+		dms.FloatPositive += 360
 	}
 	return dms
 }
@@ -86,17 +54,6 @@ func (dms *DMS) HMS() HMS {
 	return NewHMSHours(hours)
 }
 
-// Returns +/- degrees.  If you need 0->360, add 360.0 to negative results!
-func (dms *DMS) toFloat() float64 {
-	ret := math.Abs(float64(dms.Degrees))
-	ret += float64(dms.Minutes) / 60.0
-	ret += dms.Seconds / 3600.0
-	if dms.Degrees < 0 {
-		ret *= -1
-	}
-	return ret
-}
-
 // Convert Degrees to hours. +-/12
 func (dms *DMS) Hours() float64 {
 	return dms.Float / 15.0
@@ -0,0 +1,173 @@
+package telescope
+
+/*
+ * Low precision positions of the planets and the Moon so they can be
+ * selected from the LX200 object library.  Planets use the Keplerian
+ * elements from https://ssd.jpl.nasa.gov/planets/approx_pos.html (good to
+ * an arcminute or so between 1800 and 2050) and the Moon uses the low
+ * precision formula from the Astronomical Almanac.  All positions are
+ * geocentric, so the Moon may be off by up to a degree due to parallax.
+ */
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	OBLIQUITY_J2000 = 23.43928 // degrees
+)
+
+type SolarSystemBody int
+
+const (
+	Mercury SolarSystemBody = iota
+	Venus
+	EarthMoonBarycenter
+	Mars
+	Jupiter
+	Saturn
+	Uranus
+	Neptune
+	Moon
+)
+
+// J2000 value & rate per century
+type orbitalElements struct {
+	a, aDot       float64 // semi-major axis (AU)
+	e, eDot       float64 // eccentricity
+	i, iDot       float64 // inclination
+	l, lDot       float64 // mean longitude
+	peri, periDot float64 // longitude of perihelion
+	node, nodeDot float64 // longitude of the ascending node
+}
+
+var planetElements = map[SolarSystemBody]orbitalElements{
+	Mercury: {
+		0.38709927, 0.00000037, 0.20563593, 0.00001906, 7.00497902, -0.00594749,
+		252.25032350, 149472.67411175, 77.45779628, 0.16047689, 48.33076593, -0.12534081,
+	},
+	Venus: {
+		0.72333566, 0.00000390, 0.00677672, -0.00004107, 3.39467605, -0.00078890,
+		181.97909950, 58517.81538729, 131.60246718, 0.00268329, 76.67984255, -0.27769418,
+	},
+	EarthMoonBarycenter: {
+		1.00000261, 0.00000562, 0.01671123, -0.00004392, -0.00001531, -0.01294668,
+		100.46457166, 35999.37244981, 102.93768193, 0.32327364, 0.0, 0.0,
+	},
+	Mars: {
+		1.52371034, 0.00001847, 0.09339410, 0.00007882, 1.84969142, -0.00813131,
+		-4.55343205, 19140.30268499, -23.94362959, 0.44441088, 49.55953891, -0.29257343,
+	},
+	Jupiter: {
+		5.20288700, -0.00011607, 0.04838624, -0.00013253, 1.30439695, -0.00183714,
+		34.39644051, 3034.74612775, 14.72847983, 0.21252668, 100.47390909, 0.20469106,
+	},
+	Saturn: {
+		9.53667594, -0.00125060, 0.05386179, -0.00050991, 2.48599187, 0.00193609,
+		49.95424423, 1222.49362201, 92.59887831, -0.41897216, 113.66242448, -0.28867794,
+	},
+	Uranus: {
+		19.18916464, -0.00196176, 0.04725744, -0.00004397, 0.77263783, -0.00242939,
+		313.23810451, 428.48202785, 170.95427630, 0.40805281, 74.01692503, 0.04240589,
+	},
+	Neptune: {
+		30.06992276, 0.00026291, 0.00859048, 0.00005105, 1.77004347, 0.00035372,
+		-55.12002969, 218.45945325, 44.96476227, -0.32241464, 131.78422574, -0.00508664,
+	},
+}
+
+var j2000Epoch = time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+
+/*
+ * Julian centuries since J2000.  We don't use TimeToJ2000() which counts
+ * days the way the sidereal time formula expects.
+ */
+func julianCenturies(t time.Time) float64 {
+	return t.Sub(j2000Epoch).Hours() / 24.0 / 36525.0
+}
+
+func sinDeg(deg float64) float64 {
+	return math.Sin(deg * math.Pi / 180.0)
+}
+
+func cosDeg(deg float64) float64 {
+	return math.Cos(deg * math.Pi / 180.0)
+}
+
+// Heliocentric J2000 ecliptic coordinates in AU, T is centuries since J2000
+func (el orbitalElements) position(T float64) (float64, float64, float64) {
+	a := el.a + el.aDot*T
+	e := el.e + el.eDot*T
+	i := el.i + el.iDot*T
+	l := el.l + el.lDot*T
+	peri := el.peri + el.periDot*T
+	node := el.node + el.nodeDot*T
+
+	w := peri - node
+	m := math.Mod(l-peri, 360.0)
+	if m > 180.0 {
+		m -= 360.0
+	} else if m < -180.0 {
+		m += 360.0
+	}
+
+	// solve Kepler's equation for the eccentric anomaly
+	eDeg := e * 180.0 / math.Pi
+	E := m + eDeg*sinDeg(m)
+	for n := 0; n < 10; n++ {
+		dE := (m - (E - eDeg*sinDeg(E))) / (1.0 - e*cosDeg(E))
+		E += dE
+		if math.Abs(dE) < 1e-6 {
+			break
+		}
+	}
+
+	xp := a * (cosDeg(E) - e)
+	yp := a * math.Sqrt(1.0-e*e) * sinDeg(E)
+
+	x := (cosDeg(w)*cosDeg(node)-sinDeg(w)*sinDeg(node)*cosDeg(i))*xp +
+		(-sinDeg(w)*cosDeg(node)-cosDeg(w)*sinDeg(node)*cosDeg(i))*yp
+	y := (cosDeg(w)*sinDeg(node)+sinDeg(w)*cosDeg(node)*cosDeg(i))*xp +
+		(-sinDeg(w)*sinDeg(node)+cosDeg(w)*cosDeg(node)*cosDeg(i))*yp
+	z := sinDeg(w)*sinDeg(i)*xp + cosDeg(w)*sinDeg(i)*yp
+	return x, y, z
+}
+
+// Converts J2000 ecliptic x, y, z to RA (hours) & Dec (degrees)
+func eclipticToRaDec(x, y, z float64) (float64, float64) {
+	xeq := x
+	yeq := y*cosDeg(OBLIQUITY_J2000) - z*sinDeg(OBLIQUITY_J2000)
+	zeq := y*sinDeg(OBLIQUITY_J2000) + z*cosDeg(OBLIQUITY_J2000)
+
+	ra := math.Atan2(yeq, xeq) * 180.0 / math.Pi / 15.0
+	if ra < 0 {
+		ra += 24.0
+	}
+	dec := math.Atan2(zeq, math.Sqrt(xeq*xeq+yeq*yeq)) * 180.0 / math.Pi
+	return ra, dec
+}
+
+// Returns the geocentric RA (hours) & Dec (degrees) of the body at the given time
+func (body SolarSystemBody) Position(t time.Time) (float64, float64) {
+	T := julianCenturies(t)
+
+	if body == Moon {
+		lon := 218.32 + 481267.881*T +
+			6.29*sinDeg(135.0+477198.87*T) -
+			1.27*sinDeg(259.3-413335.36*T) +
+			0.66*sinDeg(235.7+890534.22*T) +
+			0.21*sinDeg(269.9+954397.74*T) -
+			0.19*sinDeg(357.5+35999.05*T) -
+			0.11*sinDeg(186.5+966404.03*T)
+		lat := 5.13*sinDeg(93.3+483202.02*T) +
+			0.28*sinDeg(228.2+960400.89*T) -
+			0.28*sinDeg(318.3+6003.15*T) -
+			0.17*sinDeg(217.6-407332.21*T)
+		return eclipticToRaDec(cosDeg(lat)*cosDeg(lon), cosDeg(lat)*sinDeg(lon), sinDeg(lat))
+	}
+
+	ex, ey, ez := planetElements[EarthMoonBarycenter].position(T)
+	px, py, pz := planetElements[body].position(T)
+	return eclipticToRaDec(px-ex, py-ey, pz-ez)
+}
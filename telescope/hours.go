@@ -21,55 +21,29 @@ type HMS struct {
 
 // New HMS via hours, minutes & seconds
 func NewHMS(hours int, minutes int, seconds float64) HMS {
-	ret := HMS{
+	return HMS{
 		Hours:   hours,
 		Minutes: minutes,
 		Seconds: seconds,
-		Float:   0.0,
+		Float:   joinSexagesimal(hours, minutes, seconds),
 	}
-	ret.Float = ret.toFloat()
-	return ret
 }
 
 // New HMS via hours.frac_hours
 func NewHMSHours(hours float64) HMS {
-	var hrs, min, sec float64
-	if hours > 0 {
-		hrs = math.Floor(hours)
-		fracHours := hours - hrs
-		min = math.Floor(fracHours * 60.0)
-
-		sec = fracHours - (float64(min) / 60.0)
-		sec *= 3600.0
-	} else {
-		hrs = math.Ceil(hours)
-		fracHours := hours - hrs
-		min = math.Abs(math.Ceil(fracHours * 60.0))
-		sec = fracHours + (float64(min) / 60.0)
-		sec *= -3600.0
-	}
-
+	h, m, s := splitSexagesimal(hours)
 	return HMS{
-		Hours:   int(hrs),
-		Minutes: int(min),
-		Seconds: sec,
+		Hours:   h,
+		Minutes: m,
+		Seconds: s,
 		Float:   hours,
 	}
 }
 
 // Sometime we express things in hours min.frac_min
 func NewHMSShort(hours int, minutes float64) HMS {
-	min := math.Floor(minutes)
-	sec := (minutes - min) * 60.0
-
-	hms := HMS{
-		Hours:   hours,
-		Minutes: int(min),
-		Seconds: sec,
-		Float:   0.0,
-	}
-	hms.Float = hms.toFloat()
-	return hms
+	whole := math.Floor(minutes)
+	return NewHMS(hours, int(whole), (minutes-whole)*60.0)
 }
 
 // Convert HMS to DMS
@@ -80,13 +54,7 @@ func (dms *HMS) ToDMS() DMS {
 
 // converts H:M:S to a hours.frac_hours
 func (hms *HMS) toFloat() float64 {
-	ret := math.Abs(float64(hms.Hours))
-	ret += float64(hms.Minutes) / 60.0
-	ret += hms.Seconds / 3600.0
-	if hms.Hours < 0 {
-		ret *= -1.0
-	}
-	return ret
+	return joinSexagesimal(hms.Hours, hms.Minutes, hms.Seconds)
 }
 
 // Convert to 0->360
@@ -0,0 +1,92 @@
+package telescope
+
+/*
+ * Shared connection handling for all of our protocols.  Each Listener
+ * accepts clients on its own address and hands them off to its
+ * TelescopeProtocol & Alpaca Telescope.  Multiple Listeners can run in
+ * the same process and share the same Telescope.
+ */
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/synfinatic/alpacascope/alpaca"
+)
+
+type Listener struct {
+	Name      string // name for logging, usually the protocol
+	Address   string // ip:port or serial device
+	Protocol  TelescopeProtocol
+	Telescope *alpaca.Telescope
+	ln        net.Listener
+	closed    atomic.Bool
+}
+
+func NewListener(name, address string, proto TelescopeProtocol, scope *alpaca.Telescope) *Listener {
+	return &Listener{
+		Name:      name,
+		Address:   address,
+		Protocol:  proto,
+		Telescope: scope,
+	}
+}
+
+// Open our TCP socket
+func (l *Listener) Listen() error {
+	ln, err := net.Listen("tcp", l.Address)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %s", l.Address, err.Error())
+	}
+	l.ln = ln
+	return nil
+}
+
+// Use an already open net.Listener such as a serial port
+func (l *Listener) SetListener(ln net.Listener) {
+	l.ln = ln
+}
+
+/*
+ * Accept and process clients until Close() is called.  Clients of the
+ * same Listener are processed one at a time since our protocols keep
+ * per-listener state such as the LX200 date & site: a second client can
+ * connect, but gets no replies until the first one disconnects.  Use a
+ * listener per client program instead.  HTTPProtocols such as the
+ * WebSocket are the exception and serve all their clients at once.
+ */
+func (l *Listener) Serve() {
+	log.Infof("Waiting for %s clients on %s", l.Name, l.Address)
+	if hp, ok := l.Protocol.(HTTPProtocol); ok {
+		err := http.Serve(l.ln, hp.Handler(l.Telescope)) // nolint:gosec
+		if err != nil && !l.closed.Load() {
+			log.Errorf("Error serving %s: %s", l.Name, err.Error())
+		}
+		return
+	}
+
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			if l.closed.Load() {
+				return
+			}
+			log.Warnf("Error calling Accept() for %s: %s", l.Name, err.Error())
+			continue
+		}
+
+		log.Debugf("Accepted %s connection from: %s", l.Name, conn.RemoteAddr().String())
+		l.Protocol.HandleConnection(conn, l.Telescope)
+	}
+}
+
+func (l *Listener) Close() error {
+	l.closed.Store(true)
+	if l.ln == nil {
+		return nil
+	}
+	return l.ln.Close()
+}
@@ -2,23 +2,59 @@ package telescope
 
 import (
 	"fmt"
+	"io"
 	"math"
 	"net"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/synfinatic/alpacascope/alpaca"
 )
 
+const (
+	LX200_MAX_PULSE   = 9999 // msec, :MgnDDDD#
+	LX200_SIDEREAL_HZ = 60.0 // tracking rates are in Hz of a 60Hz synchronous motor
+	LX200_HZ_STEP     = 0.1  // :T+# and :T-#
+)
+
+// arcsec/sec for each Alpaca DriveRate
+var driveRates = map[alpaca.DriveRate]float64{
+	alpaca.DriveSidereal: 15.041,
+	alpaca.DriveLunar:    14.685,
+	alpaca.DriveSolar:    15.0,
+	alpaca.DriveKing:     15.0369,
+}
+
+// Target set by the client, which is per connection and only sent to the mount on a slew/sync
+type lx200Target struct {
+	ra      float64 // :Sr
+	dec     float64 // :Sd
+	alt     float64 // :Sa
+	az      float64 // :Sz
+	haveRA  bool
+	haveDec bool
+	haveAlt bool
+	haveAz  bool
+}
+
 type LX200 struct {
 	AutoTrack      bool // ensure tracking is enabled for goto
 	HighPrecision  bool
-	TwentyFourHour bool // :H#
+	Profile        LX200Profile
+	Focuser        *alpaca.Focuser    // nil if not configured
+	Passthrough    []LX200Passthrough // unsupported commands to send to the driver
+	Sites          *SiteStore         // :W1# - :W4#
+	SettleTime     time.Duration      // :D# reports slewing until the mount has settled
+	TwentyFourHour bool               // :H#
 	MaxSlew        float64
 	MinSlew        float64
 	SlewRate       int
 	UTCOffset      float64
+	LowerLimit     float64 // :So lowest altitude we will slew to
+	UpperLimit     float64 // :Sh highest altitude we will slew to
 	haveTime       bool
 	haveDate       bool
 	hour           int
@@ -27,6 +63,19 @@ type LX200 struct {
 	day            int
 	month          int
 	year           int
+	findingHome    bool       // :hF/:hC
+	stateLock      sync.Mutex // everything above & below which clients can change
+	lastSlew       time.Time  // last time we saw the mount slewing
+	customHz       float64    // :TM# tracking rate
+	customRate     bool       // :TM# is selected
+	focusLock      sync.Mutex
+	focusSpeed     int       // :F1# - :F4#
+	focusStop      chan bool // closed to stop :F+# or :F-#
+	guider         axisGuider
+	libraryLock    sync.Mutex
+	library        string         // :LC# catalog selected via :Lo
+	object         *CatalogObject // selected via :L, nil if none
+	objectFilter   string         // :Gy#
 }
 
 func NewLX200(autoTrack, highPrecision, twentyfourhr bool, rates map[string]float64, utcoffset float64) *LX200 {
@@ -38,23 +87,120 @@ func NewLX200(autoTrack, highPrecision, twentyfourhr bool, rates map[string]floa
 		MinSlew:        rates["Minimum"],
 		SlewRate:       int(rates["Maximum"]),
 		UTCOffset:      utcoffset,
+		UpperLimit:     90.0,
+		Sites:          NewSiteStore(""),
+		customHz:       LX200_SIDEREAL_HZ,
+		focusSpeed:     LX200_FOCUS_FASTEST,
+		library:        CATALOG_NGC,
+		objectFilter:   LX200_DEFAULT_FILTER,
 	}
+	state.Profile, _ = GetLX200Profile(DEFAULT_LX200_PROFILE)
 	return &state
 }
 
+var (
+	highPrecisionOption = ProtocolOption{
+		Name:    "high-precision",
+		Label:   "LX200 default to High Precision",
+		Help:    "Default to High Precision in LX200 mode",
+		Type:    OptionBool,
+		Default: "false",
+	}
+	lx200ProfileOption = ProtocolOption{
+		Name:    "lx200-profile",
+		Label:   "LX200 Model",
+		Help:    "LX200 model to emulate",
+		Type:    OptionString,
+		Default: DEFAULT_LX200_PROFILE,
+		Choices: LX200ProfileNames(),
+	}
+	focuserIDOption = ProtocolOption{
+		Name:  "focuser-id",
+		Label: "ASCOM Focuser ID",
+		Help:  "Alpaca FocuserID for LX200 focuser commands",
+		Type:  OptionInt,
+	}
+	settleTimeOption = ProtocolOption{
+		Name:    "settle-time",
+		Label:   "LX200 Settle Time (sec)",
+		Help:    "Seconds after a slew before LX200 :D# reports it is complete",
+		Type:    OptionInt,
+		Default: "0",
+	}
+	passthroughOption = ProtocolOption{
+		Name:  "passthrough",
+		Label: "LX200 Passthrough Commands",
+		Help:  "Space separated LX200 command prefixes to send to the driver: :GX[=blind|=ACTION]",
+		Type:  OptionString,
+		Check: func(value string) error {
+			_, err := ParseLX200Passthrough(value)
+			return err
+		},
+	}
+	sitesFileOption = ProtocolOption{
+		Name:  "sites-file",
+		Label: "LX200 Sites File",
+		Help:  "File to save LX200 :W1# - :W4# sites (default: ~/.alpacascope/sites.json)",
+		Type:  OptionString,
+	}
+)
+
+func init() {
+	RegisterProtocol(Protocol{
+		Name:        "lx200",
+		Label:       "LX200",
+		Description: "Meade LX200",
+		DefaultPort: 4030,
+		Order:       20,
+		Options:     []ProtocolOption{highPrecisionOption, lx200ProfileOption, focuserIDOption, settleTimeOption, passthroughOption, sitesFileOption},
+		Factory: func(scope *alpaca.Telescope, config ProtocolConfig) (TelescopeProtocol, error) {
+			return newLX200(scope, config), nil
+		},
+	})
+}
+
+func newLX200(scope *alpaca.Telescope, config ProtocolConfig) *LX200 {
+	minmax, err := scope.GetAxisRates(alpaca.AxisAzmRa)
+	if err != nil {
+		log.Errorf("Unable to query axis rates: %s", err.Error())
+	}
+	lx200 := NewLX200(config.AutoTrack, config.Bool("high-precision"), true, minmax, 100000)
+	if profile, err := GetLX200Profile(config.String("lx200-profile")); err == nil {
+		lx200.Profile = profile
+	}
+	if settle, err := strconv.ParseUint(config.String("settle-time"), 10, 32); err == nil {
+		lx200.SettleTime = time.Duration(settle) * time.Second
+	}
+	lx200.Passthrough, _ = ParseLX200Passthrough(config.String("passthrough")) // already validated
+	sitesFile := config.String("sites-file")
+	if sitesFile == "" {
+		sitesFile = DefaultSitesPath()
+	}
+	lx200.Sites = NewSiteStore(sitesFile)
+	if id := config.String("focuser-id"); id != "" {
+		focuserID, _ := strconv.ParseUint(id, 10, 32) // already validated
+		lx200.Focuser = alpaca.NewFocuser(uint32(focuserID), scope.Client())
+	}
+	return lx200
+}
+
 func (state *LX200) HandleConnection(conn net.Conn, t *alpaca.Telescope) {
 	buf := make([]byte, 1024)
+	target := &lx200Target{}
 
 	defer conn.Close()
+	// don't leave the focuser running if the client goes away mid :F+#
+	defer state.haltFocus()
 	rlen, err := conn.Read(buf)
-	for err != nil {
+	for err == nil {
 		/*
 		 * LX200 has a single byte command <0x06> and variable length commands
 		 * which start with a ':' and end with a '#'.  Also, clients may send
 		 * multiple commands at once :-/
 		 */
+		cmds := buf[:rlen]
 		for rlen > 0 {
-			reply, consumed := state.lx200Command(t, rlen, buf)
+			reply, consumed := state.lx200Command(t, target, rlen, cmds)
 			if len(reply) > 0 {
 				_, err = conn.Write(reply)
 				if err != nil {
@@ -62,23 +208,23 @@ func (state *LX200) HandleConnection(conn net.Conn, t *alpaca.Telescope) {
 				}
 			} else {
 				// many LX200 commands don't generate a reply
-				log.Debugf("command '%s' returned a zero length reply", string(buf[0:consumed]))
+				log.Debugf("command '%s' returned a zero length reply", string(cmds[0:consumed]))
 			}
 			rlen -= consumed
-			buf = buf[consumed:]
+			cmds = cmds[consumed:]
 			if rlen > 0 {
-				log.Debugf("processing remaining command(s) in buffer: '%s'", string(buf[0:rlen]))
+				log.Debugf("processing remaining command(s) in buffer: '%s'", string(cmds[0:rlen]))
 			}
 		}
 		rlen, err = conn.Read(buf)
 	}
 	// Will get this any time the client sends a Fin, so don't log that
-	if err.Error() != "EOF" {
+	if err != io.EOF {
 		log.Errorf("conn.Read() returned error: %s", err.Error())
 	}
 }
 
-func (state *LX200) lx200Command(t *alpaca.Telescope, cmdlen int, buf []byte) ([]byte, int) {
+func (state *LX200) lx200Command(t *alpaca.Telescope, target *lx200Target, cmdlen int, buf []byte) ([]byte, int) {
 	var consumed int
 	var retVal []byte
 	ret := ""
@@ -120,11 +266,22 @@ func (state *LX200) lx200Command(t *alpaca.Telescope, cmdlen int, buf []byte) ([
 		 * we have to split them up and process one at a time
 		 */
 
-		commands := string(buf)
+		commands := string(buf[0:cmdlen])
 		endOfCommand := strings.Index(commands, "#")
+		if endOfCommand < 0 {
+			log.Errorf("Unterminated command: %s", commands)
+			return retVal, cmdlen
+		}
 		consumed = endOfCommand + 1
 		cmd := commands[0:consumed]
 		log.Debugf("Consumed %d of %d bytes in buffer", consumed, cmdlen)
+		if cmd == "#" {
+			// some clients send a stray '#' to clear the mount's buffer
+			return retVal, consumed
+		} else if len(cmd) < 3 {
+			log.Errorf("Unexpected/Invalid command: %s", cmd)
+			return retVal, consumed
+		}
 
 		// Variable len commands, but we can alway match on the first 3 bytes
 		switch cmd[0:3] {
@@ -134,41 +291,51 @@ func (state *LX200) lx200Command(t *alpaca.Telescope, cmdlen int, buf []byte) ([
 		 * :$B - Backlash compensation
 		 * :B - Reticule accessory control
 		 * :CL# - Sync with object by selenographic coordinates
-		 * :D - Distance bars
 		 * :f - Fan control
-		 * :F - Focuser control works very differently... unclear how to support
 		 * :g - GPS
 		 * :G0, :G1, :G2 - no idea what this is :(
 		 * :Gb - Browse brigher magnitude limit
 		 * :GF - field diameter
 		 * :GF - faint magnitude limit
-		 * :Gh - get high limit
 		 * :Gl - Larger size limit
-		 * :GM, :GN, :GO, :GP - get site (1, 2, 3, 4) name
-		 * :Go - Get lower limit
 		 * :Gq - Get minimum quality for find operation
-		 * :GVD - Get firmware date
-		 * :GVN - Get firmware version
-		 * :GVP - Get product name
-		 * :GVT - Get firmware time
-		 * :Gy# - Get deepsky object string
 		 *
-		 * :h - home position commands
+		 * :hS, :hQ - set park position
 		 * :I - initialize scope
-		 * :L - object library commands
 		 * :$Q - PEC control
 		 *
 		 * :r - field derotator
 		 */
 		case ":CM":
 			// Sync with current target
-			err = t.PutSyncToTarget()
+			if target.haveRA && target.haveDec {
+				err = t.PutSyncToCoordinates(target.ra, target.dec)
+			} else {
+				err = t.PutSyncToTarget()
+			}
 			if err != nil {
 				log.Errorf("Unable to sync on target: %s", err.Error())
 			} else {
-				ret = "M31 EX GAL MAG 3.5 SZ178.0'#" // static string like Autostars/LX200GPS
+				ret = state.syncReply()
+			}
+
+		case ":D#":
+			// distance bars: one bar until the slew is complete
+			if state.isSlewing(t) {
+				ret = "\x7f#"
+			} else {
+				ret = "#"
 			}
 
+		case ":F+", ":F-", ":FQ", ":FF", ":FS", ":F1", ":F2", ":F3", ":F4",
+			":FA", ":FB", ":FG", ":FR":
+			// focuser control
+			ret, err = state.focuserCommand(cmd)
+
+		case ":Gy":
+			// deep sky object classes for :LN# and :LB#
+			ret = state.getObjectFilter() + "#"
+
 		case ":GA":
 			// telescope altitude based on precision config
 			alt, err := t.GetAltitude()
@@ -176,31 +343,32 @@ func (state *LX200) lx200Command(t *alpaca.Telescope, cmdlen int, buf []byte) ([
 				log.Errorf("Unable to get telescope altitude (:GA#): %s", err.Error())
 				alt = 0.0
 			}
-			ret = DegreesToStr(alt, state.HighPrecision) + "#"
+			ret = LX200Degrees(alt, state.highPrecision()) + "#"
 
 		case ":Ga":
 			// get local time in 12hr format: HH:MM:SS#
+			ret = state.formatTime(t, false) + "#"
 
 		case ":GL":
-			// local time in 24hr format: HH:MM:SS#
+			// local time in 24hr format (or 12hr via :H#): HH:MM:SS#
+			ret = state.formatTime(t, state.twentyFourHour()) + "#"
 
 		case ":GC":
-			// Get current date: MM/DD/YY#
-			t, err := t.GetUTCDate()
+			// Get current local date: MM/DD/YY#
+			now, err := state.localTime(t)
 			if err != nil {
 				log.Errorf("Unable to get telescope time (:GC#): %s", err.Error())
-				t = time.Unix(0, 0)
 			}
-			y, m, d := t.Date()
-			if y > 2000 {
-				y -= 2000
-			} else if y > 1900 {
-				y -= 1900
-			}
-			ret = fmt.Sprintf("%02d/%02d/%02d#", m, d, y)
+			y, m, d := now.Date()
+			ret = fmt.Sprintf("%02d/%02d/%02d#", m, d, y%100)
 
 		case ":Gc":
 			// Get calendar format: 12# or 24#
+			if state.twentyFourHour() {
+				ret = "24#"
+			} else {
+				ret = "12#"
+			}
 
 		case ":GD":
 			// telescope declination based on precision config
@@ -209,7 +377,7 @@ func (state *LX200) lx200Command(t *alpaca.Telescope, cmdlen int, buf []byte) ([
 				log.Errorf("Unable to get telescope declination (:GD#): %s", err.Error())
 				alt = 0.0
 			}
-			ret = DegreesToStr(alt, state.HighPrecision) + "#"
+			ret = LX200Degrees(alt, state.highPrecision()) + "#"
 
 		case ":GZ":
 			// telescope azimuth baesd on precision config
@@ -218,7 +386,7 @@ func (state *LX200) lx200Command(t *alpaca.Telescope, cmdlen int, buf []byte) ([
 				log.Errorf("Unable to get telescope azimuth (:GZ#): %s", err.Error())
 				az = 0.0
 			}
-			ret = DegreesToStr(az, state.HighPrecision) + "#"
+			ret = LX200Azimuth(az, state.highPrecision()) + "#"
 
 		case ":GR":
 			// telescope RA based on precision config
@@ -227,28 +395,75 @@ func (state *LX200) lx200Command(t *alpaca.Telescope, cmdlen int, buf []byte) ([
 				log.Errorf("Unable to get telescope right ascension (:GR#): %s", err.Error())
 				ra = 0.0
 			}
-			ret = DegreesToStr(ra, state.HighPrecision) + "#"
+			ret = LX200Hours(ra, state.highPrecision()) + "#"
+
+		case ":Gr":
+			// get target RA, from the mount if we don't have one
+			ra := target.ra
+			if !target.haveRA {
+				if ra, err = t.GetTargetRightAscension(); err != nil {
+					log.Errorf("Unable to get target right ascension (:Gr#): %s", err.Error())
+					ra, err = 0.0, nil
+				}
+			}
+			ret = LX200Hours(ra, state.highPrecision()) + "#"
 
 		case ":Gd":
-			// get target declination
-			dec, err := t.GetTargetDeclination()
-			if err != nil {
-				log.Errorf("Unable to get target declination (:Gd#): %s", err.Error())
-				dec = 0.0
+			// get target declination, from the mount if we don't have one
+			dec := target.dec
+			if !target.haveDec {
+				if dec, err = t.GetTargetDeclination(); err != nil {
+					log.Errorf("Unable to get target declination (:Gd#): %s", err.Error())
+					dec, err = 0.0, nil
+				}
+			}
+			ret = LX200Degrees(dec, state.highPrecision()) + "#"
+
+		case ":GV":
+			// product identity, unless the profile doesn't support it
+			if state.Profile.ProductName == "" {
+				log.Debugf("%s is not supported by the %s profile", cmd, state.Profile.Name)
+				break
+			}
+			switch cmd {
+			case ":GVP#":
+				ret = state.Profile.ProductName + "#"
+			case ":GVN#":
+				ret = state.Profile.FirmwareVersion + "#"
+			case ":GVD#":
+				ret = state.Profile.FirmwareDate + "#"
+			case ":GVT#":
+				ret = state.Profile.FirmwareTime + "#"
+			default:
+				log.Errorf("unsupported command: '%s'", cmd)
 			}
-			ret = DegreesToStr(dec, state.HighPrecision) + "#"
 
 		case ":GG":
-			// get UTC offset time
+			// get UTC offset: hours added to local time to get UTC: sHH.H#
+			ret = state.Profile.FormatUTCOffset(state.utcOffset())
+
+		case ":GS":
+			// get local sidereal time: HH:MM:SS#
+			now, err := t.GetUTCDate()
+			if err != nil {
+				log.Errorf("Unable to get telescope time (:GS#): %s", err.Error())
+				now = time.Now()
+			}
+			long, err := t.GetSiteLongitude()
+			if err != nil {
+				log.Errorf("Unable to get site longitude (:GS#): %s", err.Error())
+				long = 0.0
+			}
+			ret = FormatHHMMSS.Format(LocalSiderealTime(now, long)) + "#"
 
 		case ":Gg":
-			// Get site longitude
+			// Get site longitude with West positive
 			long, err := t.GetSiteLongitude()
 			if err != nil {
 				log.Errorf("Unable to get site longitude (:Gg#): %s", err.Error())
 				long = 0.0
 			}
-			ret = DegreesToLong(long) + "#"
+			ret = DegreesToLong(-long) + "#"
 
 		case ":Gt":
 			// Get site latitude
@@ -259,19 +474,106 @@ func (state *LX200) lx200Command(t *alpaca.Telescope, cmdlen int, buf []byte) ([
 			}
 			ret = DegreesToLat(lat) + "#"
 
+		case ":GM", ":GN", ":GO", ":GP":
+			// get site 1, 2, 3 or 4 name
+			ret = state.Sites.Site(lx200SiteIndex(cmd[2])).Name + "#"
+
+		case ":Gh":
+			// get high limit: sDD*#
+			_, upper := state.limits()
+			ret = fmt.Sprintf("%+03d*#", int(upper))
+
+		case ":Go":
+			// get lower limit: DD*#
+			lower, _ := state.limits()
+			ret = fmt.Sprintf("%02d*#", int(lower))
+
+		case ":GT":
+			// Get tracking rate: TT.T# Hz
+			ret = fmt.Sprintf("%04.1f#", state.trackingHz(t))
+
+		case ":GW":
+			// LX200GPS status: mount type, tracking and alignment
+			ret = state.mountStatus(t)
+
+		case ":GU":
+			// OnStep status flags
+			ret = state.statusFlags(t)
+
+		case ":LM", ":LC", ":LS", ":Lo", ":Ls", ":LN", ":LB", ":LI":
+			// object library
+			ret, err = state.libraryCommand(t, target, cmd)
+
+		case ":hP":
+			// park
+			err = park(t)
+			if state.Profile.ParkReply {
+				ret = lx200Bool(err == nil)
+			}
+
+		case ":hW", ":hR":
+			// wake up/restore parked telescope
+			err = unpark(t, state.AutoTrack)
+			if state.Profile.ParkReply && cmd[2] == 'R' {
+				ret = lx200Bool(err == nil)
+			}
+
+		case ":hF", ":hC":
+			// find/calibrate home
+			err = state.findHome(t)
+			// returns nothing
+
+		case ":hN":
+			// sleep: stop the motors without moving
+			err = t.PutTracking(alpaca.NotTracking)
+			// returns nothing
+
+		case ":h?":
+			// home status: 0 = not found, 1 = at home, 2 = searching
+			atHome, aerr := t.GetAtHome()
+			slewing, serr := t.GetSlewing()
+			state.stateLock.Lock()
+			if aerr == nil && atHome {
+				ret = "1"
+				state.findingHome = false
+			} else if serr == nil && slewing && state.findingHome {
+				ret = "2"
+			} else {
+				ret = "0"
+				state.findingHome = false
+			}
+			state.stateLock.Unlock()
+
 		case ":H#":
 			// switch between 12/24hr clock mode
+			state.stateLock.Lock()
 			state.TwentyFourHour = !state.TwentyFourHour
+			state.stateLock.Unlock()
 			// returns nothing
 
 		case ":MA":
-			// slew to target alt/az not supported
-			ret = "1" // fault
+			// slew to target alt/az: 0 on success, 1 on fault
+			err = state.slewToAltAz(t, target)
+			if err != nil {
+				ret = "1"
+			} else {
+				state.slewed()
+				ret = "0"
+			}
+
+		case ":Mg":
+			// pulse guide :MgnDDDD# for DDDD msec
+			ms, perr := strconv.Atoi(strings.TrimSuffix(cmd[4:], "#"))
+			if perr != nil || ms < 0 || ms > LX200_MAX_PULSE {
+				log.Errorf("Invalid pulse guide: '%s'", cmd)
+			} else {
+				err = state.pulseGuide(t, cmd[3], ms)
+			}
+			// returns nothing
 
 		case ":P#":
 			// toggle high precision
-			state.HighPrecision = !state.HighPrecision
-			if state.HighPrecision {
+			if state.togglePrecision() {
 				ret = "HIGH PRECISION"
 			} else {
 				ret = "LOW PRECISION"
@@ -307,24 +609,8 @@ func (state *LX200) lx200Command(t *alpaca.Telescope, cmdlen int, buf []byte) ([
 			// returns nothing
 
 		case ":MS":
-			// slew to target
-			if state.AutoTrack {
-				// auto-enable tracking?
-				mode, err := t.GetTracking()
-				if err != nil {
-					log.Errorf("Unable to get tracking mode: %s", err.Error())
-				} else {
-					if mode == alpaca.NotTracking {
-						err = t.PutTracking(alpaca.AltAz) // need any non-NotTracking value for true
-						if err != nil {
-							log.Errorf("Unable to auto-enable tracking: %s", err.Error())
-						}
-					}
-				}
-			}
-			err = t.PutSlewToTargetAsync()
-			// we don't get any good/bad answer from Alpaca, so always say success
-			ret = "0"
+			// slew to target: 0 on success, 1<reason># or 2<reason># on failure
+			ret = state.slewToTarget(t, target)
 
 		case ":Q#":
 			// halt slewing
@@ -344,79 +630,69 @@ func (state *LX200) lx200Command(t *alpaca.Telescope, cmdlen int, buf []byte) ([
 			// returns nothing
 
 		case ":RG":
-			// slew to slowest
-			state.SlewRate = 1
+			if cmd == ":RG#" {
+				// slew to slowest
+				state.setSlewRate(1)
+			} else {
+				// same as :RgSS.S#
+				err = state.setGuideRate(t, cmd, alpaca.AxisAzmRa, alpaca.AxisAltDec)
+			}
+			// returns nothing
+
+		case ":Rg":
+			// set both guide rates to SS.S arcsec/sec
+			err = state.setGuideRate(t, cmd, alpaca.AxisAzmRa, alpaca.AxisAltDec)
+			// returns nothing
+
+		case ":RA":
+			// set RA guide rate to DDD.D deg/sec
+			err = state.setGuideRate(t, cmd, alpaca.AxisAzmRa)
+			// returns nothing
+
+		case ":RE":
+			// set Dec guide rate to DDD.D deg/sec
+			err = state.setGuideRate(t, cmd, alpaca.AxisAltDec)
 			// returns nothing
 
 		case ":RC":
 			// slew to 2nd slowest
-			state.SlewRate = 2
+			state.setSlewRate(2)
 			// returns nothing
 
 		case ":RM":
 			// slew to 2nd fastest
-			state.SlewRate = int(state.MaxSlew) - 1
+			state.setSlewRate(int(state.MaxSlew) - 1)
 			// returns nothing
 
 		case ":RS":
 			// slew at max rate
-			state.SlewRate = int(state.MaxSlew)
+			state.setSlewRate(int(state.MaxSlew))
 			// returns nothing
 
 		case ":Sd":
-			// Set target Declination
-			var degrees, min, sec int
-			var sign byte
-			var dms DMS
-			switch strings.Count(cmd, ":") {
-			case 2:
-				// sDD:MM:SS
-				_, err = fmt.Sscanf(cmd, ":Sd%c%02d*%02d:%02d#", &sign, &degrees, &min, &sec)
-				if err != nil {
-					log.Errorf("Error parsing '%s': %s", cmd, err.Error())
-					ret = "0"
-				}
-				if sign == 0x45 { // look for '-'
-					degrees *= -1
-				}
-				dms = NewDMS(degrees, min, float64(sec))
-			case 1:
-				// sDD:MM
-				_, err = fmt.Sscanf(cmd, ":Sd%c%02d*%02d#", &sign, &degrees, &min)
-				if err != nil {
-					log.Errorf("Error parsing '%s': %s", cmd, err.Error())
-					ret = "0"
-				}
-				if sign == 0x45 { // look for '-'
-					degrees *= -1
-				}
-				dms = NewDMSShort(degrees, float64(min))
-			default:
-				log.Errorf("Unable to parse %s", cmd)
+			// Set target Declination: sDD*MM, sDD*MM:SS or sDD*MM'SS
+			dec, err := ParseSexagesimalRange(cmd[3:], -90.0, 90.0)
+			if err != nil {
+				log.Errorf("Error parsing '%s': %s", cmd, err.Error())
 				ret = "0"
-			}
-			if ret == "" {
-				err = t.PutTargetDeclination(dms.Float)
-				if err != nil {
-					ret = "0"
-				} else {
-					ret = "1"
-				}
+			} else {
+				target.dec = dec
+				target.haveDec = true
+				ret = "1"
 			}
 
 		case ":Sg":
-			// Set site longitude: :SgDDD*MM#
-			var deg, min int
-			_, err = fmt.Sscanf(cmd, ":Sg%03d*%02d#", &deg, &min)
+			// Set site longitude: :SgDDD*MM# with West positive
+			long, err := ParseSexagesimalRange(cmd[3:], -360.0, 360.0)
 			if err != nil {
 				log.Errorf("Error parsing '%s': %s", cmd, err.Error())
 				ret = "0"
 			} else {
-				dms := NewDMS(deg, min, 0)
-				err = t.PutSiteLongitude(dms.FloatPositive)
+				err = t.PutSiteLongitude(LX200LongToDegrees(long))
 				if err != nil {
 					ret = "0"
 				} else {
+					state.saveSite(t)
 					ret = "1"
 				}
 			}
@@ -426,122 +702,210 @@ func (state *LX200) lx200Command(t *alpaca.Telescope, cmdlen int, buf []byte) ([
 		 * only one.  This means we have to assume that SkySafari/etc will send
 		 * all three.  Pretty sure :SC must be last, but who knows?
 		 *
-		 * If SkySafari uses "LX200 Classic" then setting date/time will cause
-		 * SkySafari to hang for about 30sec until it times out.  LX200 GPS
-		 * does not seem to have this issue.
+		 * The reply to :SC differs by model and SkySafari's "LX200 Classic"
+		 * will hang for about 30sec unless it gets both lines of text, so
+		 * use the matching --lx200-profile.
 		 */
 		case ":SG":
 			// set TZ offset hours: :SGsHH.H
 			var sign byte
 			var hrsFloat float64
-			var hrsInt int
 
 			ret = "1"
 
-			// this is what the docs say
-			_, err = fmt.Sscanf(cmd, ":SG%c%2.1f#", &sign, &hrsFloat)
-			if err != nil {
-				// and this is what SkySafari actually sends :(
-				_, err = fmt.Sscanf(cmd, ":SG%c%02d#", &sign, &hrsInt)
-				if err != nil {
-					log.Errorf("Error parsing '%s': %s", cmd, err.Error())
-					ret = "0"
-				} else {
-					hrsFloat = float64(hrsInt)
-				}
+			// the docs say sHH.H but SkySafari sends sHH and %f reads both
+			_, err = fmt.Sscanf(cmd, ":SG%c%f#", &sign, &hrsFloat)
+			if err == nil && sign != '+' && sign != '-' {
+				err = fmt.Errorf("missing sign")
 			}
-			if err == nil {
+			if err != nil {
+				log.Errorf("Error parsing '%s': %s", cmd, err.Error())
+				ret = "0"
+			} else {
 				if sign == '-' {
 					hrsFloat *= -1
 				}
-				state.UTCOffset = hrsFloat
+				state.setUTCOffset(hrsFloat)
+				state.Sites.SetUTCOffset(hrsFloat)
 				err = state.SendDateTime(t)
 			}
 
+		case ":Sa":
+			// Set target altitude: :SasDD*MM# or :SasDD*MM'SS#
+			alt, err := ParseSexagesimalRange(cmd[3:], -90.0, 90.0)
+			if err != nil {
+				log.Errorf("Error parsing '%s': %s", cmd, err.Error())
+				ret = "0"
+			} else {
+				target.alt = alt
+				target.haveAlt = true
+				ret = "1"
+			}
+
+		case ":Sz":
+			// Set target azimuth: :SzDDD*MM# or :SzDDD*MM'SS#
+			az, err := ParseSexagesimal(cmd[3:])
+			if err == nil && (az < 0.0 || az >= 360.0) {
+				err = fmt.Errorf("azimuth is out of range")
+			}
+			if err != nil {
+				log.Errorf("Error parsing '%s': %s", cmd, err.Error())
+				ret = "0"
+			} else {
+				target.az = az
+				target.haveAz = true
+				ret = "1"
+			}
+
 		case ":SC":
 			// set local date: :SCMM/DD/YY#
-			ret = "1Updating Planetary Data#" // LOL, WTF is this???
-			_, err = fmt.Sscanf(cmd, ":SC%02d/%02d/%02d#", &state.month, &state.day, &state.year)
+			ret = state.Profile.SetDateReply
+			var month, day, year int
+			_, err = fmt.Sscanf(cmd, ":SC%02d/%02d/%02d#", &month, &day, &year)
 			if err != nil {
 				err = fmt.Errorf("unable to parse time '%s': %s", cmd, err.Error())
 				ret = "0"
 			} else {
+				state.stateLock.Lock()
+				state.month, state.day, state.year = month, day, year+2000
 				state.haveDate = true
-				state.year += 2000
+				state.stateLock.Unlock()
 				err = state.SendDateTime(t)
 			}
 
 		case ":SL":
 			// set local time: :SLHH:MM:SS in 24hr format
 			ret = "1"
-			_, err = fmt.Sscanf(cmd, ":SL%02d:%02d:%02d#", &state.hour, &state.minute, &state.second)
+			var hour, minute, second int
+			_, err = fmt.Sscanf(cmd, ":SL%02d:%02d:%02d#", &hour, &minute, &second)
 			if err != nil {
 				err = fmt.Errorf("unable to parse time '%s': %s", cmd, err.Error())
 				ret = "0"
 			} else {
+				state.stateLock.Lock()
+				state.hour, state.minute, state.second = hour, minute, second
 				state.haveTime = true
+				state.stateLock.Unlock()
 				err = state.SendDateTime(t)
 			}
 
 		case ":Sr":
-			// Set target RA
-			var hour, min, sec int
-			var hms HMS
-			switch strings.Count(cmd, ":") {
-			case 3:
-				// HH:MM:SS
-				_, err = fmt.Sscanf(cmd, ":Sr%02d:%02d:%02d#", &hour, &min, &sec)
-				if err != nil {
-					log.Errorf("error parsing '%s': %s", cmd, err.Error())
-					ret = "0"
-				}
-				hms = NewHMS(hour, min, float64(sec))
-			case 2:
-				// HH:MM.T  not sure what T is.  Assuming is tenth of sec?
-				_, err = fmt.Sscanf(cmd, ":Sr%02d:%02d.%d#", &hour, &min, &sec)
-				if err != nil {
-					log.Errorf("error parsing '%s': %s", cmd, err.Error())
-					ret = "0"
-				}
-				minFloat := float64(min) + (float64(sec) / 10.0)
-				hms = NewHMSShort(hour, minFloat)
-			default:
-				log.Errorf("unable to parse %s", cmd)
+			// Set target RA: HH:MM:SS or HH:MM.T
+			ra, err := ParseSexagesimal(cmd[3:])
+			if err == nil && (ra < 0.0 || ra >= 24.0) {
+				err = fmt.Errorf("RA is out of range")
+			}
+			if err != nil {
+				log.Errorf("error parsing '%s': %s", cmd, err.Error())
 				ret = "0"
+			} else {
+				target.ra = ra
+				target.haveRA = true
+				ret = "1"
 			}
 
-			if ret == "" {
-				err = t.PutTargetRightAscension(hms.Float)
-				if err != nil {
-					ret = "0"
-				} else {
-					ret = "1"
-				}
+		case ":Sy":
+			// Set deep sky object classes: :SyGPDCO#
+			err = state.setObjectFilter(strings.TrimSuffix(cmd[3:], "#"))
+			ret = lx200Bool(err == nil)
+
+		case ":SM", ":SN", ":SO", ":SP":
+			// set site 1, 2, 3 or 4 name: :SM<string>#
+			err = state.Sites.SetName(lx200SiteIndex(cmd[2]), strings.TrimSuffix(cmd[3:], "#"))
+			ret = lx200Bool(err == nil)
+
+		case ":So", ":Sh":
+			// Set lower limit :SoDD*# or high limit :ShDD#
+			limit, perr := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(cmd[3:], "#"), "*"))
+			if perr != nil {
+				log.Errorf("Error parsing '%s': %s", cmd, perr.Error())
+				ret = "0"
+			} else {
+				ret = lx200Bool(state.setLimit(cmd[2] == 'h', float64(limit)))
 			}
 
 		case ":St":
 			// Set site latitude: :StsDD*MM#
-			var sign byte
-			var deg, min int
-			_, err = fmt.Sscanf(cmd, ":St%c%02d*%02d#", &sign, &deg, &min)
+			lat, err := ParseSexagesimalRange(cmd[3:], -90.0, 90.0)
 			if err != nil {
 				log.Errorf("error parsing '%s': %s", cmd, err.Error())
 				ret = "0"
 			} else {
-				if sign == '-' {
-					deg *= -1
-				}
-				dms := NewDMS(deg, min, 0)
-				err = t.PutSiteLatitude(dms.Float)
+				err = t.PutSiteLatitude(lat)
 				if err != nil {
 					ret = "0"
 				} else {
+					state.saveSite(t)
 					ret = "1"
 				}
 			}
 
+		case ":ST":
+			// Set tracking rate: :STTT.T# Hz
+			hz, perr := strconv.ParseFloat(strings.TrimSuffix(cmd[3:], "#"), 64)
+			if perr != nil || hz < LX200_SIDEREAL_HZ/2.0 || hz > LX200_SIDEREAL_HZ*1.5 {
+				log.Errorf("Invalid tracking rate: '%s'", cmd)
+				ret = "0"
+			} else {
+				state.stateLock.Lock()
+				state.customHz = hz
+				state.stateLock.Unlock()
+				err = state.setCustomRate(t)
+				ret = lx200Bool(err == nil)
+			}
+
+		case ":W1", ":W2", ":W3", ":W4":
+			// select site and send its location to the mount
+			site := state.Sites.Select(int(cmd[2] - '1'))
+			if site.HaveUTCOffset {
+				state.setUTCOffset(site.UTCOffset)
+			}
+			err = site.Send(t)
+			// returns nothing
+
+		/* Tracking rates */
+		case ":TQ":
+			err = state.setTrackingRate(t, alpaca.DriveSidereal)
+			// returns nothing
+
+		case ":TL":
+			err = state.setTrackingRate(t, alpaca.DriveLunar)
+			// returns nothing
+
+		case ":TS":
+			err = state.setTrackingRate(t, alpaca.DriveSolar)
+			// returns nothing
+
+		case ":TK":
+			err = state.setTrackingRate(t, alpaca.DriveKing)
+			// returns nothing
+
+		case ":TM":
+			// custom rate set via :ST, :T+ and :T-
+			err = state.setCustomRate(t)
+			// returns nothing
+
+		case ":T+", ":T-":
+			// adjust the custom rate, which applies immediately if selected
+			step := LX200_HZ_STEP
+			if cmd[2] == '-' {
+				step *= -1
+			}
+			state.stateLock.Lock()
+			state.customHz = math.Round((state.customHz+step)*10.0) / 10.0
+			selected := state.customRate
+			state.stateLock.Unlock()
+			if selected {
+				err = state.setCustomRate(t)
+			}
+			// returns nothing
+
 		default:
-			log.Errorf("unsupported command: '%s'", cmd)
+			if rule, ok := state.passthroughRule(cmd); ok {
+				ret, err = state.passthrough(t, rule, cmd)
+			} else {
+				log.Errorf("unsupported command: '%s'", cmd)
+			}
 		}
 	}
 
@@ -557,54 +921,609 @@ func (state *LX200) lx200Command(t *alpaca.Telescope, cmdlen int, buf []byte) ([
 	return retVal, consumed
 }
 
-func (state *LX200) rateToASCOM(movePostion bool) int {
-	ret := state.SlewRate
+// Turns on tracking if it is off
+func enableTracking(t *alpaca.Telescope) {
+	mode, err := t.GetTracking()
+	if err != nil {
+		log.Errorf("Unable to get tracking mode: %s", err.Error())
+	} else if mode == alpaca.NotTracking {
+		err = t.PutTracking(alpaca.AltAz) // need any non-NotTracking value for true
+		if err != nil {
+			log.Errorf("Unable to auto-enable tracking: %s", err.Error())
+		}
+	}
+}
+
+// :MS# replies for each reason checkSlew() refuses a slew
+var lx200SlewReplies = map[slewRefusal]string{
+	slewNotSupported: "1Slew Not Supported#",
+	slewParked:       "1Telescope Parked#",
+	slewBelowLimit:   "1Object Below Horizon#",
+	slewAboveLimit:   "2Object Below Higher#",
+}
+
+/*
+ * Slews to the :Sr/:Sd target after making sure the mount can get there.
+ * Returns 0 on success, 1<reason># if the slew is refused or the object
+ * is below the lower limit and 2<reason># if it is above the high limit.
+ */
+func (state *LX200) slewToTarget(t *alpaca.Telescope, target *lx200Target) string {
+	if !target.haveRA || !target.haveDec {
+		log.Errorf("Unable to slew: no target has been set")
+		return "1No Object Selected#"
+	}
+
+	lower, upper := state.limits()
+	if serr := checkSlew(t, target.ra, target.dec, lower, upper); serr != nil {
+		log.Errorf("Unable to slew: %s", serr.Error())
+		return lx200SlewReplies[serr.Refusal]
+	}
+
+	if state.AutoTrack {
+		enableTracking(t)
+	}
+	if err := t.PutSlewToCoordinatestAsync(target.ra, target.dec); err != nil {
+		log.Errorf("Unable to slew: %s", err.Error())
+		return "1Slew Refused#"
+	}
+	state.slewed()
+	return "0"
+}
+
+// Sets the lower (:So) or high (:Sh) altitude limit for :MS#
+func (state *LX200) setLimit(high bool, limit float64) bool {
+	state.stateLock.Lock()
+	defer state.stateLock.Unlock()
+	if high {
+		if limit <= state.LowerLimit || limit > 90.0 {
+			log.Errorf("Invalid high limit: %g", limit)
+			return false
+		}
+		state.UpperLimit = limit
+		return true
+	}
+	if limit < -30.0 || limit > 30.0 || limit >= state.UpperLimit {
+		log.Errorf("Invalid lower limit: %g", limit)
+		return false
+	}
+	state.LowerLimit = limit
+	return true
+}
+
+// Slews to the :Sa/:Sz target
+func (state *LX200) slewToAltAz(t *alpaca.Telescope, target *lx200Target) error {
+	if !target.haveAlt || !target.haveAz {
+		return fmt.Errorf("no alt/az target has been set")
+	}
+	return slewToAltAz(t, target.alt, target.az, state.AutoTrack)
+}
+
+/*
+ * Slews to the alt/az.  Mounts which can't slew to an alt/az are sent the
+ * equivalent RA/Dec for the current time and site.
+ */
+func slewToAltAz(t *alpaca.Telescope, alt, az float64, autoTrack bool) error {
+	canSlew, err := t.GetCanSlewAltAzAsync()
+	if err != nil {
+		log.Warnf("Unable to get canslewaltazasync: %s", err.Error())
+	}
+	if canSlew {
+		// Alpaca requires tracking to be off for alt/az slews
+		mode, err := t.GetTracking()
+		if err == nil && mode != alpaca.NotTracking {
+			if err = t.PutTracking(alpaca.NotTracking); err != nil {
+				return err
+			}
+		}
+		return t.PutSlewToAltAzAsync(az, alt)
+	}
+
+	lat, err := t.GetSiteLatitude()
+	if err != nil {
+		return fmt.Errorf("unable to get site latitude: %s", err.Error())
+	}
+	long, err := t.GetSiteLongitude()
+	if err != nil {
+		return fmt.Errorf("unable to get site longitude: %s", err.Error())
+	}
+	now, err := t.GetUTCDate()
+	if err != nil {
+		log.Warnf("Unable to get telescope time, using our clock: %s", err.Error())
+		now = time.Now()
+	}
+
+	ra, dec := AltAzToRaDec(alt, az, lat, LocalSiderealTime(now, long))
+	log.Debugf("alt %g az %g is ra %g dec %g", alt, az, ra, dec)
+	if autoTrack {
+		enableTracking(t)
+	}
+	return t.PutSlewToCoordinatestAsync(ra, dec)
+}
+
+/*
+ * Pulse guides in the given direction (n, s, e, w).  Mounts which can't
+ * pulse guide are moved at the guide rate for the same amount of time.
+ */
+func (state *LX200) pulseGuide(t *alpaca.Telescope, dir byte, ms int) error {
+	var direction alpaca.GuideDirection
+	var axis alpaca.AxisType
+	var positive bool
+
+	// same directions as :Mn, :Ms, :Me and :Mw
+	switch dir {
+	case 'n':
+		direction, axis, positive = alpaca.GuideNorth, alpaca.AxisAltDec, true
+	case 's':
+		direction, axis, positive = alpaca.GuideSouth, alpaca.AxisAltDec, false
+	case 'e':
+		direction, axis, positive = alpaca.GuideEast, alpaca.AxisAzmRa, false
+	case 'w':
+		direction, axis, positive = alpaca.GuideWest, alpaca.AxisAzmRa, true
+	default:
+		return fmt.Errorf("invalid pulse guide direction: %c", dir)
+	}
+
+	canPulse, err := t.GetCanPulseGuide()
+	if err != nil {
+		log.Warnf("Unable to get canpulseguide: %s", err.Error())
+	}
+	if canPulse {
+		return t.PutPulseGuide(direction, ms)
+	}
+
+	var rate float64
+	if axis == alpaca.AxisAzmRa {
+		rate, err = t.GetGuideRateRightAscension()
+	} else {
+		rate, err = t.GetGuideRateDeclination()
+	}
+	if err != nil || rate <= 0.0 {
+		rate = SIDEREAL_RATE / 2.0
+	}
+	if !positive {
+		rate *= -1
+	}
+
+	return state.guider.pulse(t, axis, rate, ms)
+}
+
+// Emulates pulse guiding for mounts which can't by moving the axis
+type axisGuider struct {
+	lock   sync.Mutex
+	timers [2]*time.Timer // by alpaca.AxisType
+	ends   [2]time.Time
+}
+
+// Moves the axis at the rate (deg/sec) for ms
+func (g *axisGuider) pulse(t *alpaca.Telescope, axis alpaca.AxisType, rate float64, ms int) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if timer := g.timers[axis]; timer != nil {
+		timer.Stop()
+	}
+	if err := t.PutMoveAxis(axis, rate); err != nil {
+		return err
+	}
+	duration := time.Duration(ms) * time.Millisecond
+	g.ends[axis] = time.Now().Add(duration)
+	g.timers[axis] = time.AfterFunc(duration, func() {
+		if err := t.PutMoveAxis(axis, 0); err != nil {
+			log.Errorf("Unable to stop pulse guide: %s", err.Error())
+		}
+	})
+	return nil
+}
+
+// Is the axis being pulse guided?
+func (g *axisGuider) guiding(axis alpaca.AxisType) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return time.Now().Before(g.ends[axis])
+}
+
+/*
+ * Sets the guide rate for the given axes.  :Rg/:RG are arcsec/sec while
+ * :RA/:RE are deg/sec.
+ */
+func (state *LX200) setGuideRate(t *alpaca.Telescope, cmd string, axes ...alpaca.AxisType) error {
+	rate, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(cmd[3:], "#")), 64)
+	if err != nil || rate <= 0.0 {
+		return fmt.Errorf("invalid guide rate: '%s'", cmd)
+	}
+	if strings.EqualFold(cmd[0:3], ":RG") {
+		rate /= 3600.0
+	}
+
+	canSet, err := t.GetCanSetGuideRates()
+	if err != nil {
+		return err
+	} else if !canSet {
+		return fmt.Errorf("mount does not support setting guide rates")
+	}
+
+	for _, axis := range axes {
+		if axis == alpaca.AxisAzmRa {
+			err = t.PutGuideRateRightAscension(rate)
+		} else {
+			err = t.PutGuideRateDeclination(rate)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Is the mount slewing or still settling after a slew?
+func (state *LX200) isSlewing(t *alpaca.Telescope) bool {
+	slewing, err := t.GetSlewing()
+	if err != nil {
+		log.Errorf("Unable to get slewing: %s", err.Error())
+	} else if slewing {
+		state.slewed()
+		return true
+	}
+	state.stateLock.Lock()
+	defer state.stateLock.Unlock()
+	return time.Since(state.lastSlew) < state.SettleTime
+}
+
+// Records that the mount is slewing for isSlewing()
+func (state *LX200) slewed() {
+	state.stateLock.Lock()
+	state.lastSlew = time.Now()
+	state.stateLock.Unlock()
+}
+
+/*
+ * Returns the :GW# reply: A/P/G for the mount type, T/N for tracking and
+ * the number of alignment stars.  Alpaca has no alignment state and the
+ * mount is usable, so we always say it is aligned.
+ */
+func (state *LX200) mountStatus(t *alpaca.Telescope) string {
+	mount := "A"
+	mode, err := t.GetAlignmentMode()
+	if err != nil {
+		log.Errorf("Unable to determine alignmentmode: %s", err.Error())
+	}
+	switch mode {
+	case alpaca.AlignmentPolar:
+		mount = "P"
+	case alpaca.AlignmentGermanPolar:
+		mount = "G"
+	}
+
+	tracking := "N"
+	if tm, err := t.GetTracking(); err == nil && tm != alpaca.NotTracking {
+		tracking = "T"
+	}
+	return mount + tracking + "1#"
+}
+
+/*
+ * Returns the OnStep :GU# flags:
+ * n = not tracking, N = not slewing, p/P = not parked/parked,
+ * H = at home, G = pulse guiding and A/K/E for the mount type
+ */
+func (state *LX200) statusFlags(t *alpaca.Telescope) string {
+	flags := ""
+	if tm, err := t.GetTracking(); err != nil || tm == alpaca.NotTracking {
+		flags += "n"
+	}
+	if !state.isSlewing(t) {
+		flags += "N"
+	}
+	if atPark, err := t.GetAtPark(); err == nil && atPark {
+		flags += "P"
+	} else {
+		flags += "p"
+	}
+	if atHome, err := t.GetAtHome(); err == nil && atHome {
+		flags += "H"
+	}
+	if state.isGuiding(t) {
+		flags += "G"
+	}
+
+	mode, err := t.GetAlignmentMode()
+	if err != nil {
+		log.Errorf("Unable to determine alignmentmode: %s", err.Error())
+	}
+	switch mode {
+	case alpaca.AlignmentAltAz:
+		flags += "A"
+	case alpaca.AlignmentPolar:
+		flags += "K"
+	case alpaca.AlignmentGermanPolar:
+		flags += "E"
+	}
+	return flags + "#"
+}
+
+// Is either axis being pulse guided by the mount or our axisGuider?
+func (state *LX200) isGuiding(t *alpaca.Telescope) bool {
+	if state.guider.guiding(alpaca.AxisAzmRa) || state.guider.guiding(alpaca.AxisAltDec) {
+		return true
+	}
+	guiding, err := t.GetIsPulseGuiding()
+	return err == nil && guiding
+}
+
+// Parks the mount if it can
+func park(t *alpaca.Telescope) error {
+	canPark, err := t.GetCanPark()
+	if err != nil {
+		return err
+	} else if !canPark {
+		return fmt.Errorf("mount does not support parking")
+	}
+	return t.PutPark()
+}
+
+// Unparks the mount if it is parked
+func unpark(t *alpaca.Telescope, autoTrack bool) error {
+	atPark, err := t.GetAtPark()
+	if err != nil {
+		return err
+	}
+	if atPark {
+		canUnpark, err := t.GetCanUnpark()
+		if err != nil {
+			return err
+		} else if !canUnpark {
+			return fmt.Errorf("mount does not support unparking")
+		}
+		if err = t.PutUnpark(); err != nil {
+			return err
+		}
+	}
+	if autoTrack {
+		enableTracking(t)
+	}
+	return nil
+}
+
+func (state *LX200) findHome(t *alpaca.Telescope) error {
+	if err := findHome(t); err != nil {
+		return err
+	}
+	state.stateLock.Lock()
+	state.findingHome = true
+	state.stateLock.Unlock()
+	return nil
+}
+
+// Starts finding home if the mount can
+func findHome(t *alpaca.Telescope) error {
+	canFindHome, err := t.GetCanFindHome()
+	if err != nil {
+		return err
+	} else if !canFindHome {
+		return fmt.Errorf("mount does not support finding home")
+	}
+	return t.PutFindHome()
+}
+
+// Selects one of the standard tracking rates and clears any custom offset
+func (state *LX200) setTrackingRate(t *alpaca.Telescope, rate alpaca.DriveRate) error {
+	if err := t.PutTrackingRate(rate); err != nil {
+		return err
+	}
+	state.stateLock.Lock()
+	state.customRate = false
+	state.stateLock.Unlock()
+	if canSet, err := t.GetCanSetRightAscensionRate(); err == nil && canSet {
+		return t.PutRightAscensionRate(0.0)
+	}
+	return nil
+}
+
+// Tracks at state.customHz via an offset from sidereal
+func (state *LX200) setCustomRate(t *alpaca.Telescope) error {
+	canSet, err := t.GetCanSetRightAscensionRate()
+	if err != nil {
+		return err
+	} else if !canSet {
+		return fmt.Errorf("mount does not support custom tracking rates")
+	}
+	if err = t.PutTrackingRate(alpaca.DriveSidereal); err != nil {
+		return err
+	}
+	state.stateLock.Lock()
+	state.customRate = true
+	hz := state.customHz
+	state.stateLock.Unlock()
+	// RightAscensionRate is RA seconds per sidereal second, so 1.0 == 60Hz.
+	// Tracking faster than sidereal moves the mount west, which lowers RA.
+	return t.PutRightAscensionRate((LX200_SIDEREAL_HZ - hz) / LX200_SIDEREAL_HZ)
+}
+
+// Returns the current tracking rate in Hz
+func (state *LX200) trackingHz(t *alpaca.Telescope) float64 {
+	rate, err := t.GetTrackingRate()
+	if err != nil {
+		log.Errorf("Unable to get tracking rate: %s", err.Error())
+		rate = alpaca.DriveSidereal
+	}
+	arcsec, ok := driveRates[rate]
+	if !ok {
+		arcsec = driveRates[alpaca.DriveSidereal]
+	}
+	hz := LX200_SIDEREAL_HZ * arcsec / driveRates[alpaca.DriveSidereal]
+	if offset, err := t.GetRightAscensionRate(); err == nil {
+		hz -= offset * LX200_SIDEREAL_HZ
+	}
+	return hz
+}
+
+// Returns the 1/0 success reply used by many LX200 commands
+func lx200Bool(ok bool) string {
+	if ok {
+		return "1"
+	}
+	return "0"
+}
+
+// Is the 24hr clock (:H#) selected?
+func (state *LX200) twentyFourHour() bool {
+	state.stateLock.Lock()
+	defer state.stateLock.Unlock()
+	return state.TwentyFourHour
+}
+
+// Returns the lower (:So) and high (:Sh) altitude limits
+func (state *LX200) limits() (float64, float64) {
+	state.stateLock.Lock()
+	defer state.stateLock.Unlock()
+	return state.LowerLimit, state.UpperLimit
+}
+
+// Sets the UTC offset from :SG# or the selected site
+func (state *LX200) setUTCOffset(offset float64) {
+	state.stateLock.Lock()
+	state.UTCOffset = offset
+	state.stateLock.Unlock()
+}
+
+// Is high precision (:P#) selected?
+func (state *LX200) highPrecision() bool {
+	state.stateLock.Lock()
+	defer state.stateLock.Unlock()
+	return state.HighPrecision
+}
+
+// Toggles high precision and returns the new setting
+func (state *LX200) togglePrecision() bool {
+	state.stateLock.Lock()
+	defer state.stateLock.Unlock()
+	state.HighPrecision = !state.HighPrecision
+	return state.HighPrecision
+}
+
+// Selects the :Me/:Mw/:Mn/:Ms slew rate
+func (state *LX200) setSlewRate(rate int) {
+	state.stateLock.Lock()
+	state.SlewRate = rate
+	state.stateLock.Unlock()
+}
+
+func (state *LX200) rateToASCOM(movePostion bool) float64 {
+	state.stateLock.Lock()
+	ret := float64(state.SlewRate)
+	state.stateLock.Unlock()
 	if !movePostion {
 		ret *= -1
 	}
 	return ret
 }
 
-// Converts float to sDD*MM or sDD*MM'SS
-func DegreesToStr(deg float64, highp bool) string {
-	sign := '+'
-	if deg < 0.0 {
-		sign = '-'
+// Converts hours to HH:MM:SS (high precision) or HH:MM.T (low precision)
+func LX200Hours(hours float64, highp bool) string {
+	if highp {
+		return FormatHHMMSS.Format(hours)
 	}
-	dd := int(deg)
-	remain := deg - math.Floor(deg)
-	mm := int(remain * 60.0)
-	fracMinute := remain - float64(mm)/60.0
-	ss := int(fracMinute * 60.0 * 60.0)
+	return FormatHHMMT.Format(hours)
+}
+
+// Converts degrees to sDD*MM'SS (high precision) or sDD*MM (low precision) for Dec & Alt
+func LX200Degrees(deg float64, highp bool) string {
 	if highp {
-		return fmt.Sprintf("%c%02d*%02d'%02d", sign, dd, mm, ss)
-	} else {
-		return fmt.Sprintf("%c%02d*%02d", sign, dd, mm)
+		return FormatDDMMSS.Format(deg)
+	}
+	return FormatDDMM.Format(deg)
+}
+
+// Converts degrees to DDD*MM'SS (high precision) or DDD*MM (low precision) for Az
+func LX200Azimuth(deg float64, highp bool) string {
+	if highp {
+		return FormatDDDMMSS.Format(deg)
 	}
+	return FormatDDDMM.Format(deg)
 }
 
 // Converts float to sDDD*MM for Long.
 func DegreesToLong(deg float64) string {
-	sign := '+'
-	if deg < 0.0 {
-		sign = '-'
+	return FormatLongitudeDDDMM.Format(deg)
+}
+
+// Converts an LX200 West positive longitude to ASCOM's East positive -180 to 180
+func LX200LongToDegrees(long float64) float64 {
+	long = math.Mod(-long, 360.0)
+	if long > 180.0 {
+		long -= 360.0
+	} else if long <= -180.0 {
+		long += 360.0
 	}
-	dd := int(deg)
-	remain := deg - math.Floor(deg)
-	mm := int(remain * 60.0)
-	return fmt.Sprintf("%c%03d*%02d", sign, dd, mm)
+	return long
 }
 
 // Converts float to sDD*MM for Latitude.
 func DegreesToLat(deg float64) string {
-	sign := '+'
-	if deg < 0.0 {
-		sign = '-'
+	return FormatDDMM.Format(deg)
+}
+
+// Returns the site index for the :GM/:SM (0) to :GP/:SP (3) commands
+func lx200SiteIndex(c byte) int {
+	return int(c - 'M')
+}
+
+// Saves the mount's location as the current site
+func (state *LX200) saveSite(t *alpaca.Telescope) {
+	lat, err := t.GetSiteLatitude()
+	if err != nil {
+		log.Errorf("Unable to get site latitude: %s", err.Error())
+		return
+	}
+	long, err := t.GetSiteLongitude()
+	if err != nil {
+		log.Errorf("Unable to get site longitude: %s", err.Error())
+		return
+	}
+	elevation, err := t.GetSiteElevation()
+	if err != nil {
+		log.Warnf("Unable to get site elevation: %s", err.Error())
+	}
+	state.Sites.SetLocation(lat, long, elevation)
+}
+
+/*
+ * Returns the UTC offset set via :SG# or the offset for our timezone
+ * if the client hasn't set it
+ */
+func (state *LX200) utcOffset() float64 {
+	state.stateLock.Lock()
+	defer state.stateLock.Unlock()
+	if state.UTCOffset > 24.0 {
+		_, offset := time.Now().Zone()
+		return float64(-offset) / 3600.0
+	}
+	return state.UTCOffset
+}
+
+// Returns the mount's time in the client's timezone
+func (state *LX200) localTime(t *alpaca.Telescope) (time.Time, error) {
+	zone := time.FixedZone("", int(-state.utcOffset()*3600.0))
+	now, err := t.GetUTCDate()
+	if err != nil {
+		return time.Unix(0, 0).In(zone), err
+	}
+	return now.In(zone), nil
+}
+
+// Returns the mount's local time as HH:MM:SS in 12 or 24hr format
+func (state *LX200) formatTime(t *alpaca.Telescope, twentyFourHour bool) string {
+	now, err := state.localTime(t)
+	if err != nil {
+		log.Errorf("Unable to get telescope time: %s", err.Error())
+	}
+	hour := now.Hour()
+	if !twentyFourHour {
+		hour %= 12
+		if hour == 0 {
+			hour = 12
+		}
 	}
-	dd := int(deg)
-	remain := deg - math.Floor(deg)
-	mm := int(remain * 60.0)
-	return fmt.Sprintf("%c%02d*%02d", sign, dd, mm)
+	return fmt.Sprintf("%02d:%02d:%02d", hour, now.Minute(), now.Second())
 }
 
 /*
@@ -612,7 +1531,9 @@ func DegreesToLat(deg float64) string {
  * send the current time to Alpaca
  */
 func (state *LX200) SendDateTime(t *alpaca.Telescope) error {
+	state.stateLock.Lock()
 	if state.UTCOffset > 24.0 || !state.haveTime || !state.haveDate {
+		state.stateLock.Unlock()
 		log.Debugf("Skipping SendDateTime()")
 		return nil // nothing to do
 	}
@@ -620,7 +1541,8 @@ func (state *LX200) SendDateTime(t *alpaca.Telescope) error {
 	location, _ := time.LoadLocation("UTC")
 	date := time.Date(state.year, time.Month(state.month), state.day,
 		state.hour, state.minute, state.second, 0, location)
-	date = date.Add(time.Hour * time.Duration(state.UTCOffset))
+	date = date.Add(time.Duration(state.UTCOffset * float64(time.Hour)))
+	state.stateLock.Unlock()
 	log.Debugf("calling PutUTCDate: %v", date)
 	return t.PutUTCDate(date)
 }
@@ -0,0 +1,232 @@
+package telescope
+
+/*
+ * LX200 focuser commands via an Alpaca Focuser.  Alpaca focusers can only
+ * move to a position (or by a number of steps) so the continuous :F+# and
+ * :F-# moves are emulated by repeatedly moving a number of steps based on
+ * the selected speed until we get a :FQ#.
+ *
+ * Like the Meade hand controller, :F+# moves inward (decreasing position)
+ * and :F-# moves outward.
+ */
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/synfinatic/alpacascope/alpaca"
+)
+
+const (
+	LX200_FOCUS_INTERVAL = 250 * time.Millisecond // between continuous moves
+	LX200_FOCUS_SLOWEST  = 1                      // :FS# and :F1#
+	LX200_FOCUS_FASTEST  = 4                      // :FF# and :F4#
+)
+
+// steps per move for each speed
+var lx200FocusSteps = map[int]int32{
+	1: 10,
+	2: 50,
+	3: 250,
+	4: 1000,
+}
+
+/*
+ * Handles all the :F commands.  Returns the reply for the client which is
+ * empty for most commands.
+ */
+func (state *LX200) focuserCommand(cmd string) (string, error) {
+	arg := strings.TrimSuffix(cmd[3:], "#")
+
+	// queries reply even without a focuser
+	if state.Focuser == nil {
+		switch cmd[0:3] {
+		case ":FA", ":FB":
+			return "0", nil
+		case ":FG":
+			return "0#", nil
+		}
+		return "", fmt.Errorf("no focuser configured for '%s'", cmd)
+	}
+
+	switch cmd[0:3] {
+	case ":F+":
+		return "", state.focusContinuous(-1)
+
+	case ":F-":
+		return "", state.focusContinuous(1)
+
+	case ":FQ":
+		state.stopFocus()
+		return "", state.Focuser.PutHalt()
+
+	case ":FF":
+		state.setFocusSpeed(LX200_FOCUS_FASTEST)
+
+	case ":FS":
+		if arg == "" {
+			// Meade: slowest speed
+			state.setFocusSpeed(LX200_FOCUS_SLOWEST)
+			break
+		}
+		// OnStep: move to the absolute position
+		position, err := strconv.ParseInt(arg, 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid focuser position: '%s'", cmd)
+		}
+		return "", state.focusTo(int32(position))
+
+	case ":F1", ":F2", ":F3", ":F4":
+		state.setFocusSpeed(int(cmd[2] - '0'))
+
+	case ":FR":
+		// OnStep: move relative number of steps
+		steps, err := strconv.ParseInt(arg, 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid focuser steps: '%s'", cmd)
+		}
+		return "", state.focusBy(int32(steps))
+
+	case ":FA":
+		// OnStep: is the focuser active?
+		connected, err := state.Focuser.GetConnected()
+		return lx200Bool(err == nil && connected), err
+
+	case ":FB":
+		// is the focuser busy?
+		moving, err := state.Focuser.GetIsMoving()
+		return lx200Bool(err == nil && (moving || state.focusing())), err
+
+	case ":FG":
+		// OnStep: focuser position
+		position, err := state.Focuser.GetPosition()
+		return fmt.Sprintf("%d#", position), err
+
+	default:
+		return "", fmt.Errorf("unsupported focuser command: '%s'", cmd)
+	}
+	return "", nil
+}
+
+// Moves to the absolute position, limited to the range of the focuser
+func (state *LX200) focusTo(position int32) error {
+	absolute, err := state.Focuser.GetAbsolute()
+	if err != nil {
+		return err
+	} else if !absolute {
+		return fmt.Errorf("focuser does not support absolute positions")
+	}
+	maxStep, err := state.Focuser.GetMaxStep()
+	if err != nil {
+		return err
+	}
+	if position < 0 {
+		position = 0
+	} else if maxStep > 0 && position > maxStep {
+		position = maxStep
+	}
+	return state.Focuser.PutMove(position)
+}
+
+// Moves the number of steps, negative is inward
+func (state *LX200) focusBy(steps int32) error {
+	absolute, err := state.Focuser.GetAbsolute()
+	if err != nil {
+		return err
+	}
+	if !absolute {
+		return state.Focuser.PutMove(steps)
+	}
+	position, err := state.Focuser.GetPosition()
+	if err != nil {
+		return err
+	}
+	return state.focusTo(position + steps)
+}
+
+// Starts moving in the direction (1 or -1) until stopFocus() is called
+func (state *LX200) focusContinuous(direction int32) error {
+	state.stopFocus()
+
+	state.focusLock.Lock()
+	steps, ok := lx200FocusSteps[state.focusSpeed]
+	state.focusLock.Unlock()
+	if !ok {
+		steps = lx200FocusSteps[LX200_FOCUS_FASTEST]
+	}
+	if maxIncrement, err := state.Focuser.GetMaxIncrement(); err == nil && maxIncrement > 0 && steps > maxIncrement {
+		steps = maxIncrement
+	}
+	if err := state.focusBy(direction * steps); err != nil {
+		return err
+	}
+
+	stop := make(chan bool)
+	state.focusLock.Lock()
+	state.focusStop = stop
+	state.focusLock.Unlock()
+
+	go func(focuser *alpaca.Focuser) {
+		ticker := time.NewTicker(LX200_FOCUS_INTERVAL)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if moving, err := focuser.GetIsMoving(); err != nil || moving {
+					continue
+				}
+				if err := state.focusBy(direction * steps); err != nil {
+					log.Errorf("Unable to move focuser: %s", err.Error())
+					state.focusLock.Lock()
+					if state.focusStop == stop {
+						close(stop)
+						state.focusStop = nil
+					}
+					state.focusLock.Unlock()
+					return
+				}
+			}
+		}
+	}(state.Focuser)
+	return nil
+}
+
+func (state *LX200) setFocusSpeed(speed int) {
+	state.focusLock.Lock()
+	defer state.focusLock.Unlock()
+	state.focusSpeed = speed
+}
+
+// Is there a continuous move?
+func (state *LX200) focusing() bool {
+	state.focusLock.Lock()
+	defer state.focusLock.Unlock()
+	return state.focusStop != nil
+}
+
+// Stops any continuous move, returns true if there was one
+func (state *LX200) stopFocus() bool {
+	state.focusLock.Lock()
+	defer state.focusLock.Unlock()
+	if state.focusStop == nil {
+		return false
+	}
+	close(state.focusStop)
+	state.focusStop = nil
+	return true
+}
+
+// Stops any continuous move along with the step in progress
+func (state *LX200) haltFocus() {
+	if !state.stopFocus() || state.Focuser == nil {
+		return
+	}
+	if err := state.Focuser.PutHalt(); err != nil {
+		log.Errorf("Unable to halt focuser: %s", err.Error())
+	}
+}
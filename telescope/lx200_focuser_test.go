@@ -0,0 +1,122 @@
+package telescope
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/synfinatic/alpacascope/alpaca"
+)
+
+func TestLX200NoFocuser(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{})
+	state := newLX200Client(NewLX200(true, true, true, map[string]float64{}, 0))
+
+	assert.Equal(t, "0", lx200Reply(state, scope, ":FA#"))
+	assert.Equal(t, "0#", lx200Reply(state, scope, ":FG#"))
+	assert.Equal(t, "", lx200Reply(state, scope, ":F+#"))
+	assert.Empty(t, m.Puts())
+}
+
+func TestLX200Focuser(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"connected":    true,
+		"absolute":     true,
+		"position":     5000,
+		"maxstep":      10000,
+		"maxincrement": 500,
+		"ismoving":     false,
+	})
+	state := newLX200Client(NewLX200(true, true, true, map[string]float64{}, 0))
+	state.Focuser = alpaca.NewFocuser(0, scope.Client())
+
+	assert.Equal(t, "1", lx200Reply(state, scope, ":FA#"))
+	assert.Equal(t, "5000#", lx200Reply(state, scope, ":FG#"))
+
+	// continuous moves until :FQ#
+	lx200Reply(state, scope, ":F2#")
+	lx200Reply(state, scope, ":F+#")
+	assert.Equal(t, "1", lx200Reply(state, scope, ":FB#"))
+	time.Sleep(LX200_FOCUS_INTERVAL + LX200_FOCUS_INTERVAL/2)
+	lx200Reply(state, scope, ":FQ#")
+	assert.Equal(t, "0", lx200Reply(state, scope, ":FB#"))
+	puts := m.Puts()
+	assert.GreaterOrEqual(t, len(puts), 3)
+	for _, p := range puts[0 : len(puts)-1] {
+		assert.Equal(t, "move", p.API)
+		assert.Equal(t, "4950", p.Form.Get("Position"))
+	}
+	assert.Equal(t, "halt", puts[len(puts)-1].API)
+
+	// fastest speed is limited by maxincrement
+	lx200Reply(state, scope, ":FF#")
+	lx200Reply(state, scope, ":F-#")
+	lx200Reply(state, scope, ":FQ#")
+	puts = m.Puts()
+	assert.Equal(t, "5500", puts[0].Form.Get("Position"))
+
+	// OnStep relative & absolute moves
+	lx200Reply(state, scope, ":FR-100#")
+	lx200Reply(state, scope, ":FR+9000#")
+	lx200Reply(state, scope, ":FS2500#")
+	lx200Reply(state, scope, ":FS12000#")
+	lx200Reply(state, scope, ":FSabc#")
+	puts = m.Puts()
+	assert.Len(t, puts, 4)
+	assert.Equal(t, "4900", puts[0].Form.Get("Position"))
+	assert.Equal(t, "10000", puts[1].Form.Get("Position"))
+	assert.Equal(t, "2500", puts[2].Form.Get("Position"))
+	assert.Equal(t, "10000", puts[3].Form.Get("Position"))
+
+	// relative focusers move by steps
+	m.Set("absolute", false)
+	lx200Reply(state, scope, ":FR-100#")
+	lx200Reply(state, scope, ":F1#")
+	lx200Reply(state, scope, ":F-#")
+	lx200Reply(state, scope, ":FQ#")
+	puts = m.Puts()
+	assert.Len(t, puts, 3)
+	assert.Equal(t, "-100", puts[0].Form.Get("Position"))
+	assert.Equal(t, "10", puts[1].Form.Get("Position"))
+	assert.Equal(t, "halt", puts[2].API)
+}
+
+func TestLX200FocuserDisconnect(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"absolute": true,
+		"position": 5000,
+		"maxstep":  10000,
+		"ismoving": false,
+	})
+	state := newLX200Client(NewLX200(true, true, true, map[string]float64{}, 0))
+	state.Focuser = alpaca.NewFocuser(0, scope.Client())
+
+	client, server := net.Pipe()
+	done := make(chan bool)
+	go func() {
+		state.HandleConnection(server, scope)
+		close(done)
+	}()
+	_, err := client.Write([]byte(":F-#"))
+	assert.NoError(t, err)
+	assert.Eventually(t, state.focusing, time.Second, 10*time.Millisecond)
+
+	// the focuser stops when the client goes away without a :FQ#
+	client.Close()
+	<-done
+	assert.False(t, state.focusing())
+	puts := m.Puts()
+	assert.Equal(t, "halt", puts[len(puts)-1].API)
+
+	// but isn't halted when it wasn't moving
+	client, server = net.Pipe()
+	done = make(chan bool)
+	go func() {
+		state.HandleConnection(server, scope)
+		close(done)
+	}()
+	client.Close()
+	<-done
+	assert.Empty(t, m.Puts())
+}
@@ -0,0 +1,197 @@
+package telescope
+
+/*
+ * LX200 object library commands backed by our embedded catalog.  Selecting
+ * an object sets the target so the client can then send :MS# to slew or
+ * :CM# to sync.
+ *
+ * :LoD# only supports the NGC (0) and Caldwell (3) deep sky libraries and
+ * :LsD# only our star catalog (0).  Messier objects are always selected
+ * via :LMNNNN#.
+ */
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/synfinatic/alpacascope/alpaca"
+)
+
+const (
+	LX200_DEFAULT_FILTER = "GPDCO"                       // :Gy# all classes
+	LX200_DEFAULT_OBJECT = "M31 EX GAL MAG 3.5 SZ178.0'" // :CM# without a selected object
+)
+
+// :LoD# deep sky libraries we support
+var lx200DeepSkyLibraries = map[string]string{
+	"0": CATALOG_NGC,
+	"3": CATALOG_CALDWELL,
+}
+
+/*
+ * Handles all the :L commands.  Returns the reply for the client which is
+ * empty for most commands.
+ */
+func (state *LX200) libraryCommand(t *alpaca.Telescope, target *lx200Target, cmd string) (string, error) {
+	arg := strings.TrimSuffix(cmd[3:], "#")
+
+	switch cmd[0:3] {
+	case ":LM":
+		return "", state.selectObject(t, target, CATALOG_MESSIER, arg)
+
+	case ":LC":
+		return "", state.selectObject(t, target, state.deepSkyCatalog(), arg)
+
+	case ":LS":
+		return "", state.selectObject(t, target, CATALOG_STAR, arg)
+
+	case ":Lo":
+		catalog, ok := lx200DeepSkyLibraries[arg]
+		if !ok {
+			return "0", fmt.Errorf("unsupported deep sky library: '%s'", cmd)
+		}
+		state.libraryLock.Lock()
+		state.library = catalog
+		state.libraryLock.Unlock()
+		return "1", nil
+
+	case ":Ls":
+		if arg != "0" {
+			return "0", fmt.Errorf("unsupported star library: '%s'", cmd)
+		}
+		return "1", nil
+
+	case ":LN":
+		return "", state.browseObject(t, target, 1)
+
+	case ":LB":
+		return "", state.browseObject(t, target, -1)
+
+	case ":LI":
+		if object := state.selectedObject(); object != nil {
+			return object.Description() + "#", nil
+		}
+		return "#", nil
+
+	default:
+		return "", fmt.Errorf("unsupported library command: '%s'", cmd)
+	}
+}
+
+// Sets the :Gy# object filter. Upper case classes are included, lower case are not
+func (state *LX200) setObjectFilter(filter string) error {
+	if len(filter) != len(LX200_DEFAULT_FILTER) {
+		return fmt.Errorf("invalid object filter: '%s'", filter)
+	}
+	for i := range filter {
+		if strings.ToUpper(filter[i:i+1]) != LX200_DEFAULT_FILTER[i:i+1] {
+			return fmt.Errorf("invalid object filter: '%s'", filter)
+		}
+	}
+	state.libraryLock.Lock()
+	state.objectFilter = filter
+	state.libraryLock.Unlock()
+	return nil
+}
+
+// Returns the :Gy# object filter
+func (state *LX200) getObjectFilter() string {
+	state.libraryLock.Lock()
+	defer state.libraryLock.Unlock()
+	return state.objectFilter
+}
+
+// Returns the :CM# reply for the profile
+func (state *LX200) syncReply() string {
+	if !strings.Contains(state.Profile.SyncReply, "%s") {
+		return state.Profile.SyncReply
+	}
+	desc := LX200_DEFAULT_OBJECT
+	if object := state.selectedObject(); object != nil {
+		desc = object.Description()
+	}
+	return fmt.Sprintf(state.Profile.SyncReply, desc)
+}
+
+// Returns the :LC# catalog selected via :Lo
+func (state *LX200) deepSkyCatalog() string {
+	state.libraryLock.Lock()
+	defer state.libraryLock.Unlock()
+	return state.library
+}
+
+// Returns the currently selected object or nil
+func (state *LX200) selectedObject() *CatalogObject {
+	state.libraryLock.Lock()
+	defer state.libraryLock.Unlock()
+	return state.object
+}
+
+// Looks up the object number and makes it the target
+func (state *LX200) selectObject(t *alpaca.Telescope, target *lx200Target, catalog, number string) error {
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return fmt.Errorf("invalid object number: '%s'", number)
+	}
+	object, err := LookupObject(catalog, n)
+	if err != nil {
+		return err
+	}
+	return state.setTargetObject(t, target, object)
+}
+
+/*
+ * Selects the next (1) or previous (-1) object in the catalog of the
+ * current object (or the :Lo catalog) which passes the :Gy# filter.
+ */
+func (state *LX200) browseObject(t *alpaca.Telescope, target *lx200Target, direction int) error {
+	catalog := state.deepSkyCatalog()
+	objects := CatalogObjects(catalog)
+	current := -1
+	if direction < 0 {
+		current = len(objects)
+	}
+	if object := state.selectedObject(); object != nil {
+		catalog = object.Catalog
+		objects = CatalogObjects(catalog)
+		for i, o := range objects {
+			if o.Number == object.Number {
+				current = i
+				break
+			}
+		}
+	}
+
+	// step through the catalog wrapping around at the ends
+	filter := state.getObjectFilter()
+	for n := 1; n <= len(objects); n++ {
+		i := ((current+direction*n)%len(objects) + len(objects)) % len(objects)
+		class := objects[i].Class()
+		if class == 0 || strings.IndexByte(filter, class) >= 0 {
+			return state.setTargetObject(t, target, objects[i])
+		}
+	}
+	return fmt.Errorf("no %s objects match the filter '%s'", catalog, filter)
+}
+
+// Sets the target to the object's current position
+func (state *LX200) setTargetObject(t *alpaca.Telescope, target *lx200Target, object CatalogObject) error {
+	now, err := t.GetUTCDate()
+	if err != nil {
+		log.Warnf("Unable to get telescope time, using our clock: %s", err.Error())
+		now = time.Now()
+	}
+	ra, dec := object.Position(now)
+	log.Debugf("selected %s: ra %g dec %g", object.Label(), ra, dec)
+
+	target.ra, target.dec = ra, dec
+	target.haveRA, target.haveDec = true, true
+
+	state.libraryLock.Lock()
+	state.object = &object
+	state.libraryLock.Unlock()
+	return nil
+}
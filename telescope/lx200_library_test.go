@@ -0,0 +1,90 @@
+package telescope
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLX200Library(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"utcdate": "2021-08-20T00:00:00Z",
+	})
+	state := newLX200Client(NewLX200(true, true, true, map[string]float64{}, 0))
+
+	// nothing selected yet
+	assert.Equal(t, "#", lx200Reply(state, scope, ":LI#"))
+	assert.Equal(t, LX200_DEFAULT_OBJECT+"#", lx200Reply(state, scope, ":CM#"))
+	m.Puts()
+
+	// the target is only sent to the mount on :MS# or :CM#
+	assert.Equal(t, "", lx200Reply(state, scope, ":LM42#"))
+	assert.Empty(t, m.Puts())
+	assert.Equal(t, "05:35:24#", lx200Reply(state, scope, ":Gr#"))
+	assert.Equal(t, "-05*27'00#", lx200Reply(state, scope, ":Gd#"))
+
+	assert.Equal(t, "M42 Orion Nebula DIFF NEB MAG 4.0 SZ 85.0'#", lx200Reply(state, scope, ":LI#"))
+	assert.Equal(t, "M42 Orion Nebula DIFF NEB MAG 4.0 SZ 85.0'#", lx200Reply(state, scope, ":CM#"))
+	puts := m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "synctocoordinates", puts[0].API)
+	ra, _ := strconv.ParseFloat(puts[0].Form.Get("RightAscension"), 64)
+	assert.InDelta(t, 5.59, ra, 0.001)
+	state.Profile, _ = GetLX200Profile("classic")
+	assert.Equal(t, " M42 Orion Nebula DIFF NEB MAG 4.0 SZ 85.0'#", lx200Reply(state, scope, ":CM#"))
+	state.Profile, _ = GetLX200Profile("onstep")
+	assert.Equal(t, "N/A#", lx200Reply(state, scope, ":CM#"))
+	m.Puts()
+
+	// unknown objects don't change the target or selection
+	assert.Equal(t, "", lx200Reply(state, scope, ":LM111#"))
+	assert.Equal(t, "05:35:24#", lx200Reply(state, scope, ":Gr#"))
+	assert.Equal(t, "M42 Orion Nebula DIFF NEB MAG 4.0 SZ 85.0'#", lx200Reply(state, scope, ":LI#"))
+
+	// deep sky libraries
+	assert.Equal(t, "0", lx200Reply(state, scope, ":Lo1#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":Lo3#"))
+	lx200Reply(state, scope, ":LC14#")
+	assert.Equal(t, "C14 Double Cluster OPEN CL MAG 4.3 SZ 60.0'#", lx200Reply(state, scope, ":LI#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":Lo0#"))
+	lx200Reply(state, scope, ":LC1976#")
+	assert.Equal(t, "NGC 1976 Orion Nebula DIFF NEB MAG 4.0 SZ 85.0'#", lx200Reply(state, scope, ":LI#"))
+
+	// stars & planets
+	assert.Equal(t, "1", lx200Reply(state, scope, ":Ls0#"))
+	assert.Equal(t, "0", lx200Reply(state, scope, ":Ls1#"))
+	lx200Reply(state, scope, ":LS904#")
+	assert.Equal(t, "Jupiter PLANET#", lx200Reply(state, scope, ":LI#"))
+	assert.Equal(t, "21:58:03#", lx200Reply(state, scope, ":Gr#"))
+}
+
+func TestLX200LibraryBrowse(t *testing.T) {
+	scope, _ := newMockTelescope(t, map[string]interface{}{
+		"utcdate": "2021-08-20T00:00:00Z",
+	})
+	state := newLX200Client(NewLX200(true, true, true, map[string]float64{}, 0))
+
+	assert.Equal(t, LX200_DEFAULT_FILTER+"#", lx200Reply(state, scope, ":Gy#"))
+	assert.Equal(t, "0", lx200Reply(state, scope, ":SyGPDX#"))
+	assert.Equal(t, "0", lx200Reply(state, scope, ":SyGPD#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":SygpdCo#"))
+	assert.Equal(t, "gpdCo#", lx200Reply(state, scope, ":Gy#"))
+
+	// only globular clusters: M2, M3, M4 and wrap around from M107 to M2
+	lx200Reply(state, scope, ":LM1#")
+	lx200Reply(state, scope, ":LN#")
+	assert.Equal(t, "M2 GLOB CL MAG 6.5 SZ 16.0'#", lx200Reply(state, scope, ":LI#"))
+	lx200Reply(state, scope, ":LN#")
+	assert.Regexp(t, "^M3 GLOB CL", lx200Reply(state, scope, ":LI#"))
+	lx200Reply(state, scope, ":LB#")
+	lx200Reply(state, scope, ":LB#")
+	assert.Regexp(t, "^M107 GLOB CL", lx200Reply(state, scope, ":LI#"))
+	lx200Reply(state, scope, ":LN#")
+	assert.Regexp(t, "^M2 GLOB CL", lx200Reply(state, scope, ":LI#"))
+
+	// stars ignore the filter
+	lx200Reply(state, scope, ":LS1#")
+	lx200Reply(state, scope, ":LN#")
+	assert.Regexp(t, "^Canopus STAR", lx200Reply(state, scope, ":LI#"))
+}
@@ -0,0 +1,96 @@
+package telescope
+
+/*
+ * Optionally forwards LX200 commands we don't support to the ASCOM driver.
+ * Drivers for Meade/OnStep mounts often accept raw commands via
+ * CommandString, CommandBlind or a custom Action so this lets clients use
+ * mount specific features.  Only commands matching the allowlist are sent
+ * since we have no idea what an arbitrary command will do to the mount.
+ *
+ * The allowlist is a space separated list of command prefixes, each
+ * optionally followed by how to send it:
+ *
+ *   :GX          CommandString, relaying the reply (default)
+ *   :$QZ=blind   CommandBlind, no reply
+ *   :XX=MyAction Action "MyAction" with the command as the parameters
+ */
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/synfinatic/alpacascope/alpaca"
+)
+
+type PassthroughMethod int
+
+const (
+	PassthroughString PassthroughMethod = iota
+	PassthroughBlind
+	PassthroughAction
+)
+
+type LX200Passthrough struct {
+	Prefix string
+	Method PassthroughMethod
+	Action string // PassthroughAction only
+}
+
+// Parses the allowlist
+func ParseLX200Passthrough(spec string) ([]LX200Passthrough, error) {
+	rules := []LX200Passthrough{}
+	for _, field := range strings.Fields(spec) {
+		kv := strings.SplitN(field, "=", 2)
+		rule := LX200Passthrough{
+			Prefix: kv[0],
+			Method: PassthroughString,
+		}
+		if len(rule.Prefix) < 2 || rule.Prefix[0] != ':' || strings.Contains(rule.Prefix, "#") {
+			return rules, fmt.Errorf("invalid passthrough command: %s", field)
+		}
+		if len(kv) == 2 {
+			switch kv[1] {
+			case "string":
+			case "blind":
+				rule.Method = PassthroughBlind
+			case "":
+				return rules, fmt.Errorf("invalid passthrough method: %s", field)
+			default:
+				rule.Method = PassthroughAction
+				rule.Action = kv[1]
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Returns the first allowlist rule matching the command
+func (state *LX200) passthroughRule(cmd string) (LX200Passthrough, bool) {
+	for _, rule := range state.Passthrough {
+		if strings.HasPrefix(cmd, rule.Prefix) {
+			return rule, true
+		}
+	}
+	return LX200Passthrough{}, false
+}
+
+// Sends the command to the driver and returns the reply for the client
+func (state *LX200) passthrough(t *alpaca.Telescope, rule LX200Passthrough, cmd string) (string, error) {
+	log.Debugf("passing through '%s' to the driver", cmd)
+	switch rule.Method {
+	case PassthroughBlind:
+		return "", t.PutCommandBlind(cmd, true)
+
+	case PassthroughAction:
+		ok, err := t.SupportsAction(rule.Action)
+		if err != nil {
+			return "", fmt.Errorf("unable to get supported actions: %s", err.Error())
+		} else if !ok {
+			return "", fmt.Errorf("driver does not support action '%s' for '%s'", rule.Action, cmd)
+		}
+		return t.PutAction(rule.Action, cmd)
+	}
+	return t.PutCommandString(cmd, true)
+}
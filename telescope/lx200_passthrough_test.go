@@ -0,0 +1,73 @@
+package telescope
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLX200Passthrough(t *testing.T) {
+	rules, err := ParseLX200Passthrough("")
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+
+	rules, err = ParseLX200Passthrough(" :GX  :$QZ=blind :XX=Raw :GY=string ")
+	assert.NoError(t, err)
+	assert.Equal(t, []LX200Passthrough{
+		{Prefix: ":GX", Method: PassthroughString},
+		{Prefix: ":$QZ", Method: PassthroughBlind},
+		{Prefix: ":XX", Method: PassthroughAction, Action: "Raw"},
+		{Prefix: ":GY", Method: PassthroughString},
+	}, rules)
+
+	for _, spec := range []string{":", "GX", ":GX#", ":GX="} {
+		_, err = ParseLX200Passthrough(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestLX200Passthrough(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"put:commandstring:value": "1234#",
+		"put:action:value":        "ok#",
+		"supportedactions":        []string{"Raw"},
+	})
+	state := newLX200Client(NewLX200(true, true, true, map[string]float64{}, 0))
+
+	// disabled by default
+	assert.Equal(t, "", lx200Reply(state, scope, ":GXAS#"))
+	assert.Empty(t, m.Puts())
+
+	state.Passthrough, _ = ParseLX200Passthrough(":GX :$QZ=blind :XX=Raw :YY=Missing")
+	assert.Equal(t, "1234#", lx200Reply(state, scope, ":GXAS#"))
+	puts := m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "commandstring", puts[0].API)
+	assert.Equal(t, ":GXAS#", puts[0].Form.Get("Command"))
+	assert.Equal(t, "true", puts[0].Form.Get("Raw"))
+
+	assert.Equal(t, "", lx200Reply(state, scope, ":$QZ+#"))
+	puts = m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "commandblind", puts[0].API)
+	assert.Equal(t, ":$QZ+#", puts[0].Form.Get("Command"))
+
+	assert.Equal(t, "ok#", lx200Reply(state, scope, ":XX1#"))
+	puts = m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "action", puts[0].API)
+	assert.Equal(t, "Raw", puts[0].Form.Get("Action"))
+	assert.Equal(t, ":XX1#", puts[0].Form.Get("Parameters"))
+
+	// actions must be supported by the driver
+	assert.Equal(t, "", lx200Reply(state, scope, ":YY#"))
+	assert.Empty(t, m.Puts())
+
+	// supported commands are never passed through
+	state.Passthrough, _ = ParseLX200Passthrough(":G")
+	assert.Equal(t, "24#", lx200Reply(state, scope, ":Gc#"))
+	assert.Empty(t, m.Puts())
+
+	m.Set("put:commandstring", mockError{Number: 0x400, Message: "not implemented"})
+	assert.Equal(t, "", lx200Reply(state, scope, ":GXAS#"))
+}
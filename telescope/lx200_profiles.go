@@ -0,0 +1,115 @@
+package telescope
+
+/*
+ * LX200 emulation profiles.  Clients identify the hand controller via the
+ * :GV commands and some of them expect model specific replies, so each
+ * profile defines the identity strings and reply quirks of one model.
+ */
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	DEFAULT_LX200_PROFILE = "lx200gps"
+)
+
+type UTCOffsetFormat int
+
+const (
+	UTCOffsetTenths  UTCOffsetFormat = iota // sHH.H
+	UTCOffsetHours                          // sHH
+	UTCOffsetMinutes                        // sHH:MM
+)
+
+type LX200Profile struct {
+	Name            string // CLI/config name
+	Description     string
+	ProductName     string // :GVP#, empty if :GV commands are not supported
+	FirmwareVersion string // :GVN#
+	FirmwareDate    string // :GVD# Mmm DD YYYY
+	FirmwareTime    string // :GVT# HH:MM:SS
+	SyncReply       string // :CM# reply format, %s is the object description
+	SetDateReply    string // :SC# reply for a valid date
+	UTCOffsetFormat UTCOffsetFormat
+	ParkReply       bool // :hP# and :hR# reply 1/0
+}
+
+var lx200Profiles = map[string]LX200Profile{
+	"autostar": {
+		Name:            "autostar",
+		Description:     "Meade Autostar #497",
+		ProductName:     "Autostar",
+		FirmwareVersion: "43Eg",
+		FirmwareDate:    "Jan 27 2009",
+		FirmwareTime:    "09:36:38",
+		SyncReply:       "%s#",
+		SetDateReply:    "1Updating Planetary Data#                              #",
+		UTCOffsetFormat: UTCOffsetTenths,
+	},
+	"lx200gps": {
+		Name:            "lx200gps",
+		Description:     "Meade LX200GPS/Autostar II",
+		ProductName:     "LX2001",
+		FirmwareVersion: "4.2g",
+		FirmwareDate:    "Oct 19 2009",
+		FirmwareTime:    "16:14:28",
+		SyncReply:       "%s#",
+		SetDateReply:    "1Updating Planetary Data#",
+		UTCOffsetFormat: UTCOffsetTenths,
+	},
+	"classic": {
+		Name:        "classic",
+		Description: "Meade LX200 Classic",
+		// no :GV commands
+		SyncReply: " %s#",
+		// two # terminated lines which are shown on the handbox.  Clients
+		// wait for both, which is why sending just one hangs SkySafari
+		SetDateReply:    "1Updating        planetary data. #                                #",
+		UTCOffsetFormat: UTCOffsetHours,
+	},
+	"onstep": {
+		Name:            "onstep",
+		Description:     "OnStep",
+		ProductName:     "On-Step",
+		FirmwareVersion: "4.24k",
+		FirmwareDate:    "Jan 10 2021",
+		FirmwareTime:    "12:00:00",
+		SyncReply:       "N/A#",
+		SetDateReply:    "1",
+		UTCOffsetFormat: UTCOffsetMinutes,
+		ParkReply:       true,
+	},
+}
+
+// Returns the named profile
+func GetLX200Profile(name string) (LX200Profile, error) {
+	p, ok := lx200Profiles[name]
+	if !ok {
+		return LX200Profile{}, fmt.Errorf("unknown LX200 profile: %s", name)
+	}
+	return p, nil
+}
+
+// Returns the names of all the profiles
+func LX200ProfileNames() []string {
+	names := []string{}
+	for name := range lx200Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Formats the hours added to local time to get UTC for :GG#
+func (p LX200Profile) FormatUTCOffset(offset float64) string {
+	switch p.UTCOffsetFormat {
+	case UTCOffsetHours:
+		return fmt.Sprintf("%+03d#", int(math.Round(offset)))
+	case UTCOffsetMinutes:
+		return SexagesimalFormat{Digits: 2, Signed: true, UnitSep: ":"}.Format(offset) + "#"
+	}
+	return fmt.Sprintf("%+05.1f#", offset)
+}
@@ -0,0 +1,869 @@
+package telescope
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/synfinatic/alpacascope/alpaca"
+)
+
+// An LX200 client connection with its own target, just like HandleConnection()
+type lx200Client struct {
+	*LX200
+	target lx200Target
+}
+
+func newLX200Client(state *LX200) *lx200Client {
+	return &lx200Client{LX200: state}
+}
+
+// Sends a single command and returns the reply
+func lx200Reply(client *lx200Client, scope *alpaca.Telescope, cmd string) string {
+	reply, _ := client.lx200Command(scope, &client.target, len(cmd), []byte(cmd))
+	return string(reply)
+}
+
+func TestLX200Hours(t *testing.T) {
+	tests := map[float64][]string{ // high, low
+		0.0:                         {"00:00:00", "00:00.0"},
+		5.0 + 35.0/60 + 17.0/3600:   {"05:35:17", "05:35.3"},
+		12.5:                        {"12:30:00", "12:30.0"},
+		23.0 + 59.0/60 + 59.6/3600:  {"00:00:00", "00:00.0"}, // carry wraps the day
+		10.0 + 59.0/60 + 59.5/3600:  {"11:00:00", "11:00.0"},
+		10.0 + 29.0/60 + 57.0/3600:  {"10:29:57", "10:30.0"}, // tenths of a minute carry too
+		-1.0:                        {"23:00:00", "23:00.0"},
+		24.0:                        {"00:00:00", "00:00.0"},
+		18.0 + 36.0/60 + 56.34/3600: {"18:36:56", "18:36.9"},
+	}
+	for hours, expected := range tests {
+		assert.Equal(t, expected[0], LX200Hours(hours, true), "%g", hours)
+		assert.Equal(t, expected[1], LX200Hours(hours, false), "%g", hours)
+	}
+}
+
+func TestLX200Degrees(t *testing.T) {
+	tests := map[float64][]string{ // high, low
+		0.0:                         {"+00*00'00", "+00*00"},
+		-5.0 - 23.0/60 - 28.0/3600:  {"-05*23'28", "-05*23"},
+		-5.75:                       {"-05*45'00", "-05*45"},
+		-0.5:                        {"-00*30'00", "-00*30"},
+		89.0 + 59.0/60 + 59.7/3600:  {"+90*00'00", "+90*00"},
+		38.0 + 47.0/60 + 1.28/3600:  {"+38*47'01", "+38*47"},
+		-30.0 - 59.0/60 - 31.0/3600: {"-30*59'31", "-31*00"},
+		-0.00001:                    {"+00*00'00", "+00*00"}, // no negative zero
+	}
+	for deg, expected := range tests {
+		assert.Equal(t, expected[0], LX200Degrees(deg, true), "%g", deg)
+		assert.Equal(t, expected[1], LX200Degrees(deg, false), "%g", deg)
+	}
+}
+
+func TestLX200Azimuth(t *testing.T) {
+	tests := map[float64][]string{ // high, low
+		0.0:                          {"000*00'00", "000*00"},
+		180.25:                       {"180*15'00", "180*15"},
+		359.0 + 59.0/60 + 59.9/3600:  {"000*00'00", "000*00"},
+		-90.0:                        {"270*00'00", "270*00"},
+		45.0 + 30.0/60 + 30.0/3600:   {"045*30'30", "045*31"},
+		359.0 + 59.0/60 + 29.0/3600:  {"359*59'29", "359*59"},
+		359.0 + 59.0/60 + 31.0/3600:  {"359*59'31", "000*00"},
+		12.0 + 59.0/60 + 59.99/3600:  {"013*00'00", "013*00"},
+		271.0 + 12.0/60 + 17.49/3600: {"271*12'17", "271*12"},
+	}
+	for deg, expected := range tests {
+		assert.Equal(t, expected[0], LX200Azimuth(deg, true), "%g", deg)
+		assert.Equal(t, expected[1], LX200Azimuth(deg, false), "%g", deg)
+	}
+}
+
+/*
+ * Position replies in the high & low precision formats from the Meade
+ * LX200 command set, pointed at Vega (RA 18:36:56, Dec +38*47'01) with
+ * the target set to Rigel.  The expected strings are worked out from the
+ * spec, not captured from a real hand controller.
+ */
+func TestLX200PositionFormats(t *testing.T) {
+	scope, _ := newMockTelescope(t, map[string]interface{}{
+		"rightascension":       18.0 + 36.0/60 + 56.34/3600,
+		"declination":          38.0 + 47.0/60 + 1.28/3600,
+		"altitude":             -12.0 - 3.0/60 - 7.6/3600,
+		"azimuth":              5.0 + 6.0/60 + 59.7/3600,
+		"targetrightascension": 5.0 + 14.0/60 + 32.27/3600,
+		"targetdeclination":    -8.0 - 12.0/60 - 5.9/3600,
+	})
+	state := newLX200Client(NewLX200(false, true, true, map[string]float64{}, 0))
+
+	high := map[string]string{
+		":GR#": "18:36:56#",
+		":GD#": "+38*47'01#",
+		":GA#": "-12*03'08#",
+		":GZ#": "005*07'00#",
+		":Gr#": "05:14:32#",
+		":Gd#": "-08*12'06#",
+	}
+	for cmd, reply := range high {
+		assert.Equal(t, reply, lx200Reply(state, scope, cmd), cmd)
+	}
+
+	assert.Equal(t, "LOW PRECISION", lx200Reply(state, scope, ":P#"))
+	low := map[string]string{
+		":GR#": "18:36.9#",
+		":GD#": "+38*47#",
+		":GA#": "-12*03#",
+		":GZ#": "005*07#",
+		":Gr#": "05:14.5#",
+		":Gd#": "-08*12#",
+	}
+	for cmd, reply := range low {
+		assert.Equal(t, reply, lx200Reply(state, scope, cmd), cmd)
+	}
+}
+
+// stray '#' and short commands are skipped instead of killing the connection
+func TestLX200ShortCommands(t *testing.T) {
+	scope, _ := newMockTelescope(t, map[string]interface{}{
+		"rightascension": 18.0 + 36.0/60 + 56.34/3600,
+		"declination":    38.0 + 47.0/60 + 1.28/3600,
+	})
+	state := newLX200Client(NewLX200(false, true, true, map[string]float64{}, 0))
+	for _, cmd := range []string{"#", "Q#", ":#"} {
+		assert.Equal(t, "", lx200Reply(state, scope, cmd), cmd)
+	}
+
+	client, server := net.Pipe()
+	done := make(chan bool)
+	go func() {
+		state.HandleConnection(server, scope)
+		close(done)
+	}()
+	for cmd, reply := range map[string]string{
+		"#:GR#":  "18:36:56#",
+		"Q#:GD#": "+38*47'01#",
+	} {
+		_, err := client.Write([]byte(cmd))
+		assert.NoError(t, err)
+		buf := make([]byte, len(reply))
+		_, err = io.ReadFull(client, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, reply, string(buf), cmd)
+	}
+	client.Close()
+	<-done
+}
+
+/*
+ * Golden test of a whole client session over a connection, replaying the
+ * commands SkySafari sends to an LX200 Classic when it connects, sets the
+ * time, polls the position and does a goto.  The command stream follows
+ * what SkySafari is documented to send and the replies are worked out from
+ * the Meade spec; it is not a capture from a real session.
+ */
+func TestLX200ClientSession(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"alignmentmode":  0,
+		"rightascension": 18.0 + 36.0/60 + 56.34/3600,
+		"declination":    38.0 + 47.0/60 + 1.28/3600,
+		"canslewasync":   true,
+		"atpark":         false,
+		"slewing":        false,
+	})
+	state := newLX200Client(NewLX200(false, false, true, map[string]float64{}, 0))
+	state.Profile, _ = GetLX200Profile("classic")
+
+	client, server := net.Pipe()
+	done := make(chan bool)
+	go func() {
+		state.HandleConnection(server, scope)
+		close(done)
+	}()
+
+	session := []struct {
+		Send  string
+		Reply string
+	}{
+		{"\x06", "A"},
+		// low precision until the client asks for high
+		{":GR#", "18:36.9#"},
+		{":GD#", "+38*47#"},
+		{":P#", "HIGH PRECISION"},
+		{":SG+08#", "1"},
+		{":SL19:00:00#", "1"},
+		{":SC12/26/20#", state.Profile.SetDateReply},
+		// position polling, both commands in one write
+		{":GR#:GD#", "18:36:56#+38*47'01#"},
+		{":Sr05:14:32#", "1"},
+		{":Sd-08*12:06#", "1"},
+		{":MS#", "0"},
+		{":D#", "#"},
+		{":Q#", ""},
+	}
+	for _, step := range session {
+		_, err := client.Write([]byte(step.Send))
+		assert.NoError(t, err)
+		if step.Reply == "" {
+			continue
+		}
+		buf := make([]byte, len(step.Reply))
+		_, err = io.ReadFull(client, buf)
+		assert.NoError(t, err)
+		assert.Equal(t, step.Reply, string(buf), step.Send)
+	}
+	client.Close()
+	<-done
+
+	apis := []string{}
+	for _, put := range m.Puts() {
+		apis = append(apis, put.API)
+	}
+	assert.Equal(t, []string{"utcdate", "slewtocoordinatesasync", "abortslew"}, apis)
+}
+
+func TestLX200SetTarget(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{})
+	state := newLX200Client(NewLX200(false, true, true, map[string]float64{}, 0))
+
+	tests := map[string]float64{ // command: target value
+		":Sd-05*30#":       -5.5,
+		":Sd-00*30#":       -0.5,
+		":Sd+45*30:36#":    45.51,
+		":Sd-45*30'36#":    -45.51,
+		":Sr05:35.3#":      5.588333333333333,
+		":Sr05:35:24#":     5.59,
+		":Sr05:35:24.36#":  5.5901,
+		":Sd+38\xdf47:24#": 38.79,
+	}
+	for cmd, value := range tests {
+		assert.Equal(t, "1", lx200Reply(state, scope, cmd), cmd)
+		if cmd[2] == 'd' {
+			assert.InDelta(t, value, state.target.dec, 1e-9, cmd)
+		} else {
+			assert.InDelta(t, value, state.target.ra, 1e-9, cmd)
+		}
+	}
+
+	// invalid values don't change the target
+	assert.Equal(t, "1", lx200Reply(state, scope, ":Sd+38*47:24#"))
+	for _, cmd := range []string{":Sd+91*00#", ":Sd+45*60#", ":Sr24:00:00#", ":Sr12:3x#"} {
+		assert.Equal(t, "0", lx200Reply(state, scope, cmd), cmd)
+	}
+	assert.Equal(t, 38.79, state.target.dec)
+
+	// the target is only sent to the mount on a slew
+	assert.Empty(t, m.Puts())
+}
+
+// connections share the mount settings but each has its own target
+func TestLX200Connections(t *testing.T) {
+	scope, _ := newMockTelescope(t, map[string]interface{}{
+		"rightascension": 5.5,
+		"utcdate":        "2021-03-20T04:05:06Z",
+		"athome":         false,
+		"slewing":        false,
+	})
+	state := NewLX200(false, true, true, map[string]float64{"Maximum": 4.0}, 0)
+	state.UTCOffset = 25.0 // not set yet
+	clients := []*lx200Client{newLX200Client(state), newLX200Client(state)}
+
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(ra int, client *lx200Client) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				lx200Reply(client, scope, fmt.Sprintf(":Sr%02d:00:00#", ra))
+				lx200Reply(client, scope, ":P#")
+				lx200Reply(client, scope, ":RS#")
+				lx200Reply(client, scope, ":GR#")
+				lx200Reply(client, scope, ":T+#")
+				lx200Reply(client, scope, fmt.Sprintf(":SG-0%d#", ra+4))
+				lx200Reply(client, scope, ":SC03/20/21#")
+				lx200Reply(client, scope, fmt.Sprintf(":SL%02d:00:00#", ra))
+				lx200Reply(client, scope, ":GL#")
+				lx200Reply(client, scope, ":H#")
+				lx200Reply(client, scope, fmt.Sprintf(":So%02d*#", ra))
+				lx200Reply(client, scope, ":Go#")
+				lx200Reply(client, scope, ":h?#")
+			}
+		}(i+1, client)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1.0, clients[0].target.ra)
+	assert.Equal(t, 2.0, clients[1].target.ra)
+	assert.True(t, state.HighPrecision) // toggled 100 times
+	assert.Equal(t, 4, state.SlewRate)
+	assert.True(t, state.TwentyFourHour)
+	assert.Contains(t, []float64{-5.0, -6.0}, state.UTCOffset)
+	assert.Contains(t, []float64{1.0, 2.0}, state.LowerLimit)
+}
+
+func TestLX200SlewToTarget(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"canslewasync":  true,
+		"atpark":        false,
+		"sitelatitude":  40.0,
+		"sitelongitude": -75.0,
+		"utcdate":       "2021-03-20T04:00:00Z",
+	})
+	state := newLX200Client(NewLX200(false, true, true, map[string]float64{}, 0))
+	now, _ := time.Parse(time.RFC3339, "2021-03-20T04:00:00Z")
+	meridian := LX200Hours(LocalSiderealTime(now, -75.0), true)
+
+	assert.Equal(t, "1No Object Selected#", lx200Reply(state, scope, ":MS#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":Sr"+meridian+"#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":Sd-20*00#"))
+	assert.Empty(t, m.Puts())
+
+	// 30deg above the southern horizon
+	assert.Equal(t, "0", lx200Reply(state, scope, ":MS#"))
+	puts := m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "slewtocoordinatesasync", puts[0].API)
+	dec, _ := strconv.ParseFloat(puts[0].Form.Get("Declination"), 64)
+	assert.Equal(t, -20.0, dec)
+
+	// limits
+	assert.Equal(t, "+90*#", lx200Reply(state, scope, ":Gh#"))
+	assert.Equal(t, "00*#", lx200Reply(state, scope, ":Go#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":Sh25#"))
+	assert.Equal(t, "2Object Below Higher#", lx200Reply(state, scope, ":MS#"))
+	assert.Equal(t, "0", lx200Reply(state, scope, ":Sh91#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":Sh90#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":So-5*#"))
+	assert.Equal(t, "-5", strconv.Itoa(int(state.LowerLimit)))
+	assert.Equal(t, "0", lx200Reply(state, scope, ":So45*#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":Sd-60*00#"))
+	assert.Equal(t, "1Object Below Horizon#", lx200Reply(state, scope, ":MS#"))
+	assert.Empty(t, m.Puts())
+
+	// refused by the mount
+	lx200Reply(state, scope, ":Sd-20*00#")
+	m.Set("put:slewtocoordinatesasync", mockError{Number: 0x40b, Message: "slewing"})
+	assert.Equal(t, "1Slew Refused#", lx200Reply(state, scope, ":MS#"))
+	m.Puts()
+
+	m.Set("atpark", true)
+	assert.Equal(t, "1Telescope Parked#", lx200Reply(state, scope, ":MS#"))
+	m.Set("canslewasync", false)
+	assert.Equal(t, "1Slew Not Supported#", lx200Reply(state, scope, ":MS#"))
+	assert.Empty(t, m.Puts())
+
+	// each connection has its own target
+	m.Set("canslewasync", true)
+	m.Set("atpark", false)
+	m.Set("put:slewtocoordinatesasync", nil)
+	first, firstServer := net.Pipe()
+	second, secondServer := net.Pipe()
+	defer first.Close()
+	defer second.Close()
+	go state.HandleConnection(firstServer, scope)
+	go state.HandleConnection(secondServer, scope)
+
+	buf := make([]byte, 64)
+	_, _ = first.Write([]byte(":Sr" + meridian + "#:Sd-20*00#"))
+	n, _ := first.Read(buf[:1])
+	n2, _ := first.Read(buf[1:2])
+	assert.Equal(t, "11", string(buf[:n+n2]))
+	_, _ = second.Write([]byte(":MS#"))
+	n, _ = second.Read(buf)
+	assert.Equal(t, "1No Object Selected#", string(buf[:n]))
+	_, _ = first.Write([]byte(":MS#"))
+	n, _ = first.Read(buf)
+	assert.Equal(t, "0", string(buf[:n]))
+}
+
+func TestLX200TimeReplies(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"utcdate":       "2020-12-27T03:00:00.25Z",
+		"sitelongitude": -(121.0 + 53.0/60 + 41.9/3600), // San Jose, CA
+	})
+	state := newLX200Client(NewLX200(false, true, true, map[string]float64{}, 8.0)) // PST
+
+	replies := map[string]string{
+		":Ga#": "07:00:00#",
+		":GL#": "19:00:00#",
+		":GC#": "12/26/20#",
+		":Gc#": "24#",
+		":GG#": "+08.0#",
+		":GS#": "01:16:40#", // SkySafari shows 1h 16m 41s
+	}
+	for cmd, reply := range replies {
+		assert.Equal(t, reply, lx200Reply(state, scope, cmd), cmd)
+	}
+
+	// :H# toggles to 12hr format
+	assert.Equal(t, "", lx200Reply(state, scope, ":H#"))
+	assert.Equal(t, "12#", lx200Reply(state, scope, ":Gc#"))
+	assert.Equal(t, "07:00:00#", lx200Reply(state, scope, ":GL#"))
+
+	m.Set("utcdate", "2021-01-01T04:30:00Z")
+	state.UTCOffset = -5.5
+	assert.Equal(t, "-05.5#", lx200Reply(state, scope, ":GG#"))
+	assert.Equal(t, "10:00:00#", lx200Reply(state, scope, ":Ga#"))
+	assert.Equal(t, "01/01/21#", lx200Reply(state, scope, ":GC#"))
+
+	state.UTCOffset = 0
+	assert.Equal(t, "04:30:00#", lx200Reply(state, scope, ":Ga#"))
+	m.Set("utcdate", "2021-01-01T00:30:00Z")
+	assert.Equal(t, "12:30:00#", lx200Reply(state, scope, ":Ga#"))
+}
+
+func TestLX200Profiles(t *testing.T) {
+	assert.Equal(t, []string{"autostar", "classic", "lx200gps", "onstep"}, LX200ProfileNames())
+	_, err := GetLX200Profile("lx90")
+	assert.Error(t, err)
+
+	scope, _ := newMockTelescope(t, map[string]interface{}{})
+	state := newLX200Client(NewLX200(false, true, true, map[string]float64{}, -5.5))
+	assert.Equal(t, DEFAULT_LX200_PROFILE, state.Profile.Name)
+
+	type profileTest struct {
+		Identity []string // :GVP#, :GVN#, :GVD#, :GVT#
+		Sync     string
+		SetDate  string
+		Offset   string
+	}
+	tests := map[string]profileTest{
+		"autostar": {
+			Identity: []string{"Autostar#", "43Eg#", "Jan 27 2009#", "09:36:38#"},
+			Sync:     "M31 EX GAL MAG 3.5 SZ178.0'#",
+			SetDate:  "1Updating Planetary Data#                              #",
+			Offset:   "-05.5#",
+		},
+		"lx200gps": {
+			Identity: []string{"LX2001#", "4.2g#", "Oct 19 2009#", "16:14:28#"},
+			Sync:     "M31 EX GAL MAG 3.5 SZ178.0'#",
+			SetDate:  "1Updating Planetary Data#",
+			Offset:   "-05.5#",
+		},
+		"classic": {
+			Identity: []string{"", "", "", ""},
+			Sync:     " M31 EX GAL MAG 3.5 SZ178.0'#",
+			SetDate:  "1Updating        planetary data. #                                #",
+			Offset:   "-06#",
+		},
+		"onstep": {
+			Identity: []string{"On-Step#", "4.24k#", "Jan 10 2021#", "12:00:00#"},
+			Sync:     "N/A#",
+			SetDate:  "1",
+			Offset:   "-05:30#",
+		},
+	}
+	for name, test := range tests {
+		state.Profile, err = GetLX200Profile(name)
+		assert.NoError(t, err)
+		for i, cmd := range []string{":GVP#", ":GVN#", ":GVD#", ":GVT#"} {
+			assert.Equal(t, test.Identity[i], lx200Reply(state, scope, cmd), "%s %s", name, cmd)
+		}
+		assert.Equal(t, test.Sync, lx200Reply(state, scope, ":CM#"), name)
+		assert.Equal(t, test.SetDate, lx200Reply(state, scope, ":SC01/02/21#"), name)
+		assert.Equal(t, test.Offset, lx200Reply(state, scope, ":GG#"), name)
+	}
+}
+
+func TestLX200SlewAltAz(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"canslewaltazasync": true,
+		"tracking":          true,
+		"sitelatitude":      40.0,
+		"sitelongitude":     -75.0,
+		"utcdate":           "2021-03-20T04:00:00Z",
+	})
+	state := newLX200Client(NewLX200(true, true, true, map[string]float64{}, 0))
+
+	// no target yet
+	assert.Equal(t, "1", lx200Reply(state, scope, ":MA#"))
+	assert.Empty(t, m.Puts())
+
+	assert.Equal(t, "1", lx200Reply(state, scope, ":Sa+30*00#"))
+	assert.Equal(t, "0", lx200Reply(state, scope, ":Sa+91*00#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":Sz180*00'00#"))
+	assert.Equal(t, "0", lx200Reply(state, scope, ":Sz360*00#"))
+
+	// mount supports alt/az slews, but tracking must be off
+	assert.Equal(t, "0", lx200Reply(state, scope, ":MA#"))
+	puts := m.Puts()
+	assert.Len(t, puts, 2)
+	assert.Equal(t, "tracking", puts[0].API)
+	assert.Equal(t, "false", puts[0].Form.Get("Tracking"))
+	assert.Equal(t, "slewtoaltazasync", puts[1].API)
+	assert.Equal(t, "180", puts[1].Form.Get("Azimuth"))
+	assert.Equal(t, "30", puts[1].Form.Get("Altitude"))
+
+	// otherwise we convert to RA/Dec
+	m.Set("canslewaltazasync", false)
+	assert.Equal(t, "0", lx200Reply(state, scope, ":MA#"))
+	puts = m.Puts()
+	assert.Equal(t, "tracking", puts[0].API) // auto-track
+	assert.Equal(t, "true", puts[0].Form.Get("Tracking"))
+	slew := puts[len(puts)-1]
+	assert.Equal(t, "slewtocoordinatesasync", slew.API)
+
+	now, _ := time.Parse(time.RFC3339, "2021-03-20T04:00:00Z")
+	ra, _ := strconv.ParseFloat(slew.Form.Get("RightAscension"), 64)
+	dec, _ := strconv.ParseFloat(slew.Form.Get("Declination"), 64)
+	assert.InDelta(t, LocalSiderealTime(now, -75.0), ra, 1e-6) // on the meridian
+	assert.InDelta(t, -20.0, dec, 1e-6)
+
+	m.Set("put:slewtocoordinatesasync", mockError{Number: 0x40b, Message: "below horizon"})
+	assert.Equal(t, "1", lx200Reply(state, scope, ":MA#"))
+}
+
+func TestLX200PulseGuide(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"canpulseguide":           true,
+		"cansetguiderates":        true,
+		"guideraterightascension": 0.002,
+		"guideratedeclination":    0.003,
+	})
+	state := newLX200Client(NewLX200(true, true, true, map[string]float64{}, 0))
+
+	assert.Equal(t, "", lx200Reply(state, scope, ":Mgn1000#"))
+	puts := m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "pulseguide", puts[0].API)
+	assert.Equal(t, "0", puts[0].Form.Get("Direction"))
+	assert.Equal(t, "1000", puts[0].Form.Get("Duration"))
+
+	lx200Reply(state, scope, ":Mgw0250#")
+	puts = m.Puts()
+	assert.Equal(t, "3", puts[0].Form.Get("Direction"))
+	assert.Equal(t, "250", puts[0].Form.Get("Duration"))
+
+	// invalid
+	lx200Reply(state, scope, ":Mgx0100#")
+	lx200Reply(state, scope, ":Mgn10000#")
+	lx200Reply(state, scope, ":Mgn#")
+	assert.Empty(t, m.Puts())
+
+	// emulated with moveaxis at the guide rate
+	m.Set("canpulseguide", false)
+	lx200Reply(state, scope, ":Mge0010#")
+	lx200Reply(state, scope, ":Mgs0010#")
+	time.Sleep(200 * time.Millisecond)
+	puts = m.Puts()
+	assert.Len(t, puts, 4)
+	moves := map[string]string{}
+	for _, p := range puts[0:2] {
+		moves[p.Form.Get("Axis")] = p.Form.Get("Rate")
+	}
+	assert.Equal(t, map[string]string{"0": "-0.002", "1": "-0.003"}, moves)
+	for _, p := range puts[2:] {
+		assert.Equal(t, "moveaxis", p.API)
+		assert.Equal(t, "0", p.Form.Get("Rate"))
+	}
+}
+
+func TestLX200GuideRate(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"cansetguiderates": true,
+	})
+	state := newLX200Client(NewLX200(true, true, true, map[string]float64{"Maximum": 4.0}, 0))
+
+	lx200Reply(state, scope, ":Rg07.2#")
+	puts := m.Puts()
+	assert.Len(t, puts, 2)
+	assert.Equal(t, "guideraterightascension", puts[0].API)
+	assert.Equal(t, "0.002", puts[0].Form.Get("GuideRateRightAscension"))
+	assert.Equal(t, "guideratedeclination", puts[1].API)
+	assert.Equal(t, "0.002", puts[1].Form.Get("GuideRateDeclination"))
+
+	lx200Reply(state, scope, ":RA0.004#")
+	puts = m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "0.004", puts[0].Form.Get("GuideRateRightAscension"))
+
+	lx200Reply(state, scope, ":RE 0.001#")
+	puts = m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "0.001", puts[0].Form.Get("GuideRateDeclination"))
+
+	// :RG# still selects the slowest slew rate
+	lx200Reply(state, scope, ":RG#")
+	assert.Equal(t, 1, state.SlewRate)
+	assert.Empty(t, m.Puts())
+
+	lx200Reply(state, scope, ":RA-1#")
+	m.Set("cansetguiderates", false)
+	lx200Reply(state, scope, ":RG10#")
+	assert.Empty(t, m.Puts())
+}
+
+func TestLX200Park(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"canpark":     true,
+		"canunpark":   true,
+		"canfindhome": true,
+		"atpark":      false,
+		"athome":      false,
+		"slewing":     true,
+		"tracking":    false,
+	})
+	state := newLX200Client(NewLX200(true, true, true, map[string]float64{}, 0))
+
+	assert.Equal(t, "", lx200Reply(state, scope, ":hP#"))
+	puts := m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "park", puts[0].API)
+
+	// unpark and restore tracking
+	m.Set("atpark", true)
+	assert.Equal(t, "", lx200Reply(state, scope, ":hW#"))
+	puts = m.Puts()
+	assert.Len(t, puts, 2)
+	assert.Equal(t, "unpark", puts[0].API)
+	assert.Equal(t, "tracking", puts[1].API)
+
+	assert.Equal(t, "0", lx200Reply(state, scope, ":h?#"))
+	assert.Equal(t, "", lx200Reply(state, scope, ":hF#"))
+	puts = m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "findhome", puts[0].API)
+	assert.Equal(t, "2", lx200Reply(state, scope, ":h?#"))
+	m.Set("athome", true)
+	assert.Equal(t, "1", lx200Reply(state, scope, ":h?#"))
+
+	lx200Reply(state, scope, ":hN#")
+	puts = m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "false", puts[0].Form.Get("Tracking"))
+
+	// not supported by the mount
+	m.Set("canpark", false)
+	m.Set("canfindhome", false)
+	lx200Reply(state, scope, ":hP#")
+	lx200Reply(state, scope, ":hC#")
+	assert.Empty(t, m.Puts())
+
+	// OnStep replies with success/failure
+	state.Profile, _ = GetLX200Profile("onstep")
+	assert.Equal(t, "0", lx200Reply(state, scope, ":hP#"))
+	m.Set("canpark", true)
+	assert.Equal(t, "1", lx200Reply(state, scope, ":hP#"))
+	m.Set("put:unpark", mockError{Number: 0x40b, Message: "failed"})
+	assert.Equal(t, "0", lx200Reply(state, scope, ":hR#"))
+}
+
+func TestLX200TrackingRate(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"cansetrightascensionrate": true,
+		"trackingrate":             0,
+		"rightascensionrate":       0.0,
+	})
+	state := newLX200Client(NewLX200(true, true, true, map[string]float64{}, 0))
+
+	assert.Equal(t, "60.0#", lx200Reply(state, scope, ":GT#"))
+
+	lx200Reply(state, scope, ":TL#")
+	puts := m.Puts()
+	assert.Len(t, puts, 2)
+	assert.Equal(t, "trackingrate", puts[0].API)
+	assert.Equal(t, "1", puts[0].Form.Get("TrackingRate"))
+	assert.Equal(t, "rightascensionrate", puts[1].API)
+	assert.Equal(t, "0", puts[1].Form.Get("RightAscensionRate"))
+	assert.Equal(t, "58.6#", lx200Reply(state, scope, ":GT#"))
+
+	lx200Reply(state, scope, ":TS#")
+	assert.Equal(t, "2", m.Puts()[0].Form.Get("TrackingRate"))
+	assert.Equal(t, "59.8#", lx200Reply(state, scope, ":GT#"))
+	lx200Reply(state, scope, ":TK#")
+	assert.Equal(t, "3", m.Puts()[0].Form.Get("TrackingRate"))
+	lx200Reply(state, scope, ":TQ#")
+	assert.Equal(t, "0", m.Puts()[0].Form.Get("TrackingRate"))
+
+	// only changes the custom rate until :TM# is selected
+	lx200Reply(state, scope, ":T+#")
+	lx200Reply(state, scope, ":T+#")
+	assert.Empty(t, m.Puts())
+	lx200Reply(state, scope, ":TM#")
+	puts = m.Puts()
+	assert.Len(t, puts, 2)
+	assert.Equal(t, "0", puts[0].Form.Get("TrackingRate"))
+	rate, _ := strconv.ParseFloat(puts[1].Form.Get("RightAscensionRate"), 64)
+	assert.InDelta(t, -0.2/60.0, rate, 1e-9)
+	assert.Equal(t, "60.2#", lx200Reply(state, scope, ":GT#"))
+
+	lx200Reply(state, scope, ":T-#")
+	puts = m.Puts()
+	assert.Len(t, puts, 2)
+	rate, _ = strconv.ParseFloat(puts[1].Form.Get("RightAscensionRate"), 64)
+	assert.InDelta(t, -0.1/60.0, rate, 1e-9)
+
+	assert.Equal(t, "1", lx200Reply(state, scope, ":ST59.5#"))
+	puts = m.Puts()
+	rate, _ = strconv.ParseFloat(puts[1].Form.Get("RightAscensionRate"), 64)
+	assert.InDelta(t, 0.5/60.0, rate, 1e-9)
+	assert.Equal(t, "59.5#", lx200Reply(state, scope, ":GT#"))
+	assert.Equal(t, "0", lx200Reply(state, scope, ":ST120.0#"))
+
+	// a positive RA rate means the mount falls behind the stars
+	m.Set("rightascensionrate", 1.0/60.0)
+	assert.Equal(t, "59.0#", lx200Reply(state, scope, ":GT#"))
+	m.Set("rightascensionrate", -1.0/60.0)
+	assert.Equal(t, "61.0#", lx200Reply(state, scope, ":GT#"))
+
+	m.Set("cansetrightascensionrate", false)
+	assert.Equal(t, "0", lx200Reply(state, scope, ":ST60.1#"))
+	lx200Reply(state, scope, ":TL#")
+	puts = m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "trackingrate", puts[0].API)
+}
+
+func TestLX200SlewStatus(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"slewing":        true,
+		"tracking":       true,
+		"canslewasync":   true,
+		"alignmentmode":  2,
+		"atpark":         false,
+		"athome":         false,
+		"ispulseguiding": false,
+	})
+	state := newLX200Client(NewLX200(false, true, true, map[string]float64{}, 0))
+
+	assert.Equal(t, "\x7f#", lx200Reply(state, scope, ":D#"))
+	assert.Equal(t, "GT1#", lx200Reply(state, scope, ":GW#"))
+	assert.Equal(t, "pE#", lx200Reply(state, scope, ":GU#"))
+
+	m.Set("slewing", false)
+	assert.Equal(t, "#", lx200Reply(state, scope, ":D#"))
+
+	// keep reporting the slew until the mount settles
+	state.SettleTime = 200 * time.Millisecond
+	assert.Equal(t, "\x7f#", lx200Reply(state, scope, ":D#"))
+	time.Sleep(state.SettleTime)
+	assert.Equal(t, "#", lx200Reply(state, scope, ":D#"))
+
+	// slews which finish before we poll still settle
+	lx200Reply(state, scope, ":Sr05:35:24#")
+	lx200Reply(state, scope, ":Sd-05*27#")
+	assert.Equal(t, "0", lx200Reply(state, scope, ":MS#"))
+	assert.Equal(t, "\x7f#", lx200Reply(state, scope, ":D#"))
+	time.Sleep(state.SettleTime)
+
+	m.Set("tracking", false)
+	m.Set("alignmentmode", 0)
+	m.Set("atpark", true)
+	m.Set("athome", true)
+	m.Set("ispulseguiding", true)
+	assert.Equal(t, "AN1#", lx200Reply(state, scope, ":GW#"))
+	assert.Equal(t, "nNPHGA#", lx200Reply(state, scope, ":GU#"))
+
+	// pulses we emulate for the mount are reported too
+	m.Set("ispulseguiding", false)
+	assert.NoError(t, state.guider.pulse(scope, alpaca.AxisAltDec, 0.003, 100))
+	assert.Equal(t, "nNPHGA#", lx200Reply(state, scope, ":GU#"))
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, "nNPHA#", lx200Reply(state, scope, ":GU#"))
+}
+
+func TestLX200LongToDegrees(t *testing.T) {
+	tests := map[float64]float64{ // West positive: East positive
+		0.0:    0.0,
+		75.0:   -75.0,
+		-75.0:  75.0,
+		285.0:  75.0,
+		180.0:  180.0,
+		-180.0: 180.0,
+		359.5:  0.5,
+	}
+	for west, east := range tests {
+		assert.Equal(t, east, LX200LongToDegrees(west), "%g", west)
+	}
+}
+
+func TestLX200Sites(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"sitelatitude":  40.0,
+		"sitelongitude": -75.0,
+		"siteelevation": 100.0,
+	})
+	state := newLX200Client(NewLX200(false, true, true, map[string]float64{}, 0))
+
+	assert.Equal(t, "+075*00#", lx200Reply(state, scope, ":Gg#")) // West positive
+	assert.Equal(t, "Site 1#", lx200Reply(state, scope, ":GM#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":SMHome#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":SNDark Site#"))
+	assert.Equal(t, "0", lx200Reply(state, scope, ":SO#"))
+	assert.Equal(t, "Home#", lx200Reply(state, scope, ":GM#"))
+	assert.Equal(t, "Dark Site#", lx200Reply(state, scope, ":GN#"))
+	assert.Equal(t, "Site 3#", lx200Reply(state, scope, ":GO#"))
+	assert.Equal(t, "Site 4#", lx200Reply(state, scope, ":GP#"))
+
+	// setting the location updates the current site
+	assert.Equal(t, "1", lx200Reply(state, scope, ":St+40*00#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":Sg075*00#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":SG+05#"))
+	puts := m.Puts()
+	assert.Equal(t, "-75", puts[1].Form.Get("SiteLongitude"))
+	assert.Equal(t, Site{
+		Name: "Home", Latitude: 40.0, Longitude: -75.0, Elevation: 100.0,
+		UTCOffset: 5.0, HaveLocation: true, HaveUTCOffset: true,
+	}, state.Sites.Site(0))
+
+	// sites without a location aren't sent to the mount
+	assert.Equal(t, "", lx200Reply(state, scope, ":W2#"))
+	assert.Empty(t, m.Puts())
+	assert.Equal(t, 1, state.Sites.CurrentSite())
+	assert.Equal(t, "1", lx200Reply(state, scope, ":St+38*30#"))
+	assert.Equal(t, "1", lx200Reply(state, scope, ":Sg120*00#"))
+	m.Set("siteelevation", 2000.0)
+	assert.Equal(t, "1", lx200Reply(state, scope, ":St+38*30#"))
+	assert.Equal(t, -120.0, state.Sites.Site(1).Longitude)
+	m.Puts()
+
+	// selecting a site sends it to the mount
+	assert.Equal(t, "", lx200Reply(state, scope, ":W1#"))
+	puts = m.Puts()
+	assert.Len(t, puts, 3)
+	assert.Equal(t, "40", puts[0].Form.Get("SiteLatitude"))
+	assert.Equal(t, "-75", puts[1].Form.Get("SiteLongitude"))
+	assert.Equal(t, "100", puts[2].Form.Get("SiteElevation"))
+	assert.Equal(t, 5.0, state.UTCOffset)
+	assert.Equal(t, "+075*00#", lx200Reply(state, scope, ":Gg#"))
+
+	lx200Reply(state, scope, ":W2#")
+	puts = m.Puts()
+	assert.Equal(t, "38.5", puts[0].Form.Get("SiteLatitude"))
+	assert.Equal(t, "2000", puts[2].Form.Get("SiteElevation"))
+	assert.Equal(t, "+120*00#", lx200Reply(state, scope, ":Gg#"))
+}
+
+func TestLX200SendDateTime(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{})
+	state := newLX200Client(NewLX200(false, true, true, map[string]float64{}, 25.0))
+
+	assert.Equal(t, "1", lx200Reply(state, scope, ":SL19:00:00#"))
+	assert.Equal(t, state.Profile.SetDateReply, lx200Reply(state, scope, ":SC12/26/20#"))
+	assert.Empty(t, m.Puts()) // no UTC offset yet
+
+	// half hour offsets aren't truncated
+	assert.Equal(t, "1", lx200Reply(state, scope, ":SG+05.5#"))
+	puts := m.Puts()
+	if assert.Len(t, puts, 1) {
+		assert.Equal(t, "utcdate", puts[0].API)
+		assert.Equal(t, "2020-12-27T00:30:00Z", puts[0].Form.Get("UTCDate"))
+	}
+
+	assert.Equal(t, "1", lx200Reply(state, scope, ":SG-03.5#"))
+	puts = m.Puts()
+	if assert.Len(t, puts, 1) {
+		assert.Equal(t, "2020-12-26T15:30:00Z", puts[0].Form.Get("UTCDate"))
+	}
+
+	// as sent by SkySafari
+	assert.Equal(t, "1", lx200Reply(state, scope, ":SG+08#"))
+	puts = m.Puts()
+	if assert.Len(t, puts, 1) {
+		assert.Equal(t, "2020-12-27T03:00:00Z", puts[0].Form.Get("UTCDate"))
+	}
+	assert.Equal(t, "0", lx200Reply(state, scope, ":SG08#"))
+	assert.Empty(t, m.Puts())
+}
@@ -0,0 +1,120 @@
+package telescope
+
+/*
+ * Fake Alpaca server so we can test our protocols against a "real" telescope
+ */
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/synfinatic/alpacascope/alpaca"
+)
+
+type mockPut struct {
+	API  string
+	Form url.Values
+}
+
+// Returned as the ErrorNumber/ErrorMessage for a "get:" or "put:" api
+type mockError struct {
+	Number  int32
+	Message string
+}
+
+type mockAlpaca struct {
+	server *httptest.Server
+	lock   sync.Mutex
+	values map[string]interface{} // GET values & GET/PUT errors by api name
+	puts   []mockPut
+}
+
+func newMockTelescope(t *testing.T, values map[string]interface{}) (*alpaca.Telescope, *mockAlpaca) {
+	m := &mockAlpaca{
+		values: values,
+		puts:   []mockPut{},
+	}
+	m.server = httptest.NewServer(http.HandlerFunc(m.handle))
+	t.Cleanup(m.server.Close)
+
+	host, port, _ := net.SplitHostPort(m.server.Listener.Addr().String())
+	p, _ := strconv.ParseInt(port, 10, 32)
+	a := alpaca.NewAlpaca(1, host, int32(p))
+	return alpaca.NewTelescope(0, alpaca.AltAz, a), m
+}
+
+func (m *mockAlpaca) handle(w http.ResponseWriter, r *http.Request) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	path := strings.Split(r.URL.Path, "/")
+	api := path[len(path)-1]
+	resp := map[string]interface{}{
+		"ErrorNumber":  0,
+		"ErrorMessage": "",
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if e, ok := m.values["get:"+api].(mockError); ok {
+			resp["ErrorNumber"] = e.Number
+			resp["ErrorMessage"] = e.Message
+			break
+		}
+		resp["Value"] = m.values[api]
+
+	case http.MethodPut:
+		_ = r.ParseForm()
+		m.puts = append(m.puts, mockPut{API: api, Form: r.PostForm})
+		if e, ok := m.values["put:"+api].(mockError); ok {
+			resp["ErrorNumber"] = e.Number
+			resp["ErrorMessage"] = e.Message
+			break
+		}
+		if v, ok := m.values["put:"+api+":value"]; ok {
+			resp["Value"] = v
+		}
+		// remember simple property values for the next GET
+		for k, v := range r.PostForm {
+			if strings.EqualFold(k, api) {
+				m.values[api] = parseMockValue(v[0])
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func parseMockValue(v string) interface{} {
+	// not strconv.ParseBool() which treats "1" & "0" as bools
+	if v == "true" || v == "false" {
+		return v == "true"
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	return v
+}
+
+// Returns all the PUTs the client has made and resets the list
+func (m *mockAlpaca) Puts() []mockPut {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	puts := m.puts
+	m.puts = []mockPut{}
+	return puts
+}
+
+func (m *mockAlpaca) Set(api string, value interface{}) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.values[api] = value
+}
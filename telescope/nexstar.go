@@ -2,8 +2,12 @@ package telescope
 
 import (
 	"fmt"
+	"io"
 	"math"
 	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	alpaca "github.com/synfinatic/alpacascope/alpaca"
@@ -11,14 +15,119 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// deg/sec for the fixed slew rates 1 to 9 like a NexStar hand controller
+const NEXSTAR_SLEW_RATES = "0.008 0.017 0.033 0.067 0.134 0.3 1 2 4"
+
+// Celestron AUX motor controller commands sent via 'P'
+const (
+	NEXSTAR_MC_MOVE_POS           = 6
+	NEXSTAR_MC_MOVE_NEG           = 7
+	NEXSTAR_MC_SEEK_DONE          = 24
+	NEXSTAR_MC_SEEK_INDEX         = 25
+	NEXSTAR_MC_FIXED_POS          = 36
+	NEXSTAR_MC_FIXED_NEG          = 37
+	NEXSTAR_MC_AUX_GUIDE          = 38
+	NEXSTAR_MC_AUX_GUIDE_ACTIVE   = 39
+	NEXSTAR_MC_ENABLE_CORDWRAP    = 56
+	NEXSTAR_MC_DISABLE_CORDWRAP   = 57
+	NEXSTAR_MC_SET_CORDWRAP_POS   = 58
+	NEXSTAR_MC_POLL_CORDWRAP      = 59
+	NEXSTAR_MC_GET_CORDWRAP_POS   = 60
+	NEXSTAR_MC_SET_AUTOGUIDE_RATE = 70
+	NEXSTAR_MC_GET_AUTOGUIDE_RATE = 71
+	NEXSTAR_MC_GET_VER            = 254
+)
+
 type NexStar struct {
 	AutoTrack bool // ensure tracking is enabled for goto
+	Profile   NexStarProfile
+	SlewRates [9]float64 // deg/sec for fixed rates 1 to 9
+	guider    axisGuider
+	rateLock  sync.Mutex
+	maxRates  map[alpaca.AxisType]map[string]float64 // GetAxisRates() cache
+	wrapLock  sync.Mutex
+	wrapPos   float64 // azimuth degrees
 }
 
 func NewNexStar(autoTrack bool) *NexStar {
+	rates, _ := ParseNexStarSlewRates(NEXSTAR_SLEW_RATES)
+	profile, _ := GetNexStarProfile(DEFAULT_NEXSTAR_PROFILE)
 	return &NexStar{
 		AutoTrack: autoTrack,
+		Profile:   profile,
+		SlewRates: rates,
+		maxRates:  map[alpaca.AxisType]map[string]float64{},
+	}
+}
+
+var (
+	mountTypeOption = ProtocolOption{
+		Name:    "mount-type",
+		Label:   "NexStar Mount Type",
+		Help:    "Mount type",
+		Type:    OptionString,
+		Default: "altaz",
+		Choices: []string{"altaz", "eqn", "eqs"},
+	}
+	nexstarProfileOption = ProtocolOption{
+		Name:    "nexstar-profile",
+		Label:   "NexStar Model",
+		Help:    "NexStar hand controller to emulate",
+		Type:    OptionString,
+		Default: DEFAULT_NEXSTAR_PROFILE,
+		Choices: NexStarProfileNames(),
+	}
+	slewRatesOption = ProtocolOption{
+		Name:    "slew-rates",
+		Label:   "NexStar Slew Rates (deg/sec)",
+		Help:    "Space separated deg/sec for the NexStar fixed slew rates 1 to 9",
+		Type:    OptionString,
+		Default: NEXSTAR_SLEW_RATES,
+		Check: func(value string) error {
+			_, err := ParseNexStarSlewRates(value)
+			return err
+		},
+	}
+)
+
+func init() {
+	RegisterProtocol(Protocol{
+		Name:        "nexstar",
+		Label:       "NexStar",
+		Description: "Celestron NexStar hand controller",
+		DefaultPort: 4030,
+		Order:       10,
+		Options:     []ProtocolOption{mountTypeOption, nexstarProfileOption, slewRatesOption},
+		Factory: func(scope *alpaca.Telescope, config ProtocolConfig) (TelescopeProtocol, error) {
+			return newNexStar(config), nil
+		},
+	})
+}
+
+func newNexStar(config ProtocolConfig) *NexStar {
+	nexstar := NewNexStar(config.AutoTrack)
+	if profile, err := GetNexStarProfile(config.String("nexstar-profile")); err == nil {
+		nexstar.Profile = profile
+	}
+	nexstar.SlewRates, _ = ParseNexStarSlewRates(config.String("slew-rates")) // already validated
+	return nexstar
+}
+
+// Parses the space separated deg/sec for the fixed slew rates 1 to 9
+func ParseNexStarSlewRates(spec string) ([9]float64, error) {
+	rates := [9]float64{}
+	fields := strings.Fields(spec)
+	if len(fields) != len(rates) {
+		return rates, fmt.Errorf("need %d slew rates, not %d", len(rates), len(fields))
 	}
+	for i, field := range fields {
+		rate, err := strconv.ParseFloat(field, 64)
+		if err != nil || rate <= 0.0 {
+			return rates, fmt.Errorf("invalid slew rate: %s", field)
+		}
+		rates[i] = rate
+	}
+	return rates, nil
 }
 
 func (n *NexStar) HandleConnection(conn net.Conn, t *alpaca.Telescope) {
@@ -26,7 +135,7 @@ func (n *NexStar) HandleConnection(conn net.Conn, t *alpaca.Telescope) {
 
 	defer conn.Close() // make sure we close connection before we leave
 	rlen, err := conn.Read(buf)
-	for err != nil {
+	for err == nil {
 		reply := n.nexstarCommand(t, rlen, buf)
 		wlen := len(reply)
 		log.Debugf("our reply %d bytes: %v", wlen, reply)
@@ -39,27 +148,32 @@ func (n *NexStar) HandleConnection(conn net.Conn, t *alpaca.Telescope) {
 				log.Errorf("only wrote %d of %d bytes", x, wlen)
 			}
 		} else {
-			log.Errorf("command '%s' returned a zero length reply", string(buf))
+			log.Errorf("command '%s' returned a zero length reply", string(buf[:rlen]))
 		}
 		rlen, err = conn.Read(buf) // blocks for next command
 	}
 
 	// Will get this any time the client sends a Fin, so don't log that
-	if err.Error() != "EOF" {
+	if err != io.EOF {
 		log.Errorf("conn.Read() returned error: %s", err.Error())
 	}
 }
 
-func (n *NexStar) nexstarCommand(t *alpaca.Telescope, len int, buf []byte) []byte {
+func (n *NexStar) nexstarCommand(t *alpaca.Telescope, rlen int, buf []byte) []byte {
 	var retVal []byte
 	ret := ""
 	var err error
 	if log.IsLevelEnabled(log.DebugLevel) {
 		var strbuf string
-		for i := 1; i < len; i++ {
+		for i := 1; i < rlen; i++ {
 			strbuf = fmt.Sprintf("%s %d", strbuf, buf[i])
 		}
-		log.Debugf("Received %d bytes [%s]: %c %s", len, string(buf[:len]), buf[0], strbuf)
+		log.Debugf("Received %d bytes [%s]: %c %s", rlen, string(buf[:rlen]), buf[0], strbuf)
+	}
+
+	if !n.Profile.Supports(buf[0]) {
+		log.Errorf("%s does not support command: %c", n.Profile.Description, buf[0])
+		return []byte{'#'}
 	}
 
 	// single byte commands
@@ -85,62 +199,49 @@ func (n *NexStar) nexstarCommand(t *alpaca.Telescope, len int, buf []byte) []byt
 			ret = fmt.Sprintf("%s#", radec.Nexstar(highPrecision))
 		}
 
-	case 'Z':
+	case 'Z', 'z':
 		// Get AZM/ALT.  Note that AZM is 0->360, while Alt is -90->90
 		azm, alt, err := t.GetAzmAlt()
 		if err != nil {
 			log.Errorf("unable to get AZM/ALT: %s", err.Error())
 		} else {
-			asmInt := uint32(azm / 360.0 * math.Pow(2, 16))
-			altInt := uint32(alt / 360.0 * math.Pow(2, 16))
-			ret = fmt.Sprintf("%04X,%04X#", asmInt, altInt)
-		}
-
-	case 'z':
-		// Get Precise AZM/ALT
-		azm, alt, err := t.GetAzmAlt()
-		if err != nil {
-			log.Errorf("unable to get AZM/ALT: %s", err.Error())
-		} else {
-			azmInt := uint32(azm / 360.0 * 4294967296.0)
-			altInt := uint32(alt / 360.0 * 4294967296.0)
-			ret = fmt.Sprintf("%08X,%08X#", azmInt, altInt)
+			altaz := AltAz{
+				Alt: alt,
+				Az:  azm,
+			}
+			ret = fmt.Sprintf("%s#", altaz.Nexstar(buf[0] == 'z'))
 		}
 
 	case 't':
-		// get tracking mode
-		mode, err := t.GetTracking()
-		if err != nil {
-			log.Errorf("unable to get tracking mode: %s", err.Error())
-		} else {
-			ret = fmt.Sprintf("%c#", mode)
+		// get tracking mode: 0 = off, 1 = Alt/Az, 2 = EQ North, 3 = EQ South
+		var mode alpaca.TrackingMode
+		mode, err = n.trackingMode(t)
+		if err == nil {
+			retVal = []byte{byte(mode), '#'}
 		}
 
 	case 'T':
 		// set tracking mode
-		var trackingMode alpaca.TrackingMode
-		_, _ = fmt.Sscanf(string(buf[1]), "%d", &trackingMode)
-		err = t.PutTracking(trackingMode)
+		err = n.setTrackingMode(t, alpaca.TrackingMode(buf[1]))
 		ret = "#"
 
 	case 'V':
 		// Get Version
-		ret = "50#"
+		retVal = n.Profile.VersionReply()
+
+	case 'v':
+		// Get hand controller variant
+		retVal = []byte{n.Profile.Variant, '#'}
 
 	case 'P':
 		// Pass through commands for Slew, GPS, RTC, etc
-		if int(buf[3]) == 254 {
+		version, ok := n.Profile.DeviceVersions[int(buf[2])]
+		if !ok {
+			log.Errorf("%s does not support P device: %d", n.Profile.Description, int(buf[2]))
+			ret = "#"
+		} else if int(buf[3]) == NEXSTAR_MC_GET_VER {
 			// Get passthrough device version
-			switch int(buf[2]) {
-			case 16, 17:
-				// mounts
-				retVal = []byte{5, 0, '#'}
-			case 176, 178:
-				// GPS & RTC
-				retVal = []byte{1, 6, '#'}
-			default:
-				log.Errorf("invalid device version device type: %d", int(buf[3]))
-			}
+			retVal = append(append([]byte{}, version...), '#')
 		} else {
 			switch int(buf[2]) {
 			case 176:
@@ -150,8 +251,7 @@ func (n *NexStar) nexstarCommand(t *alpaca.Telescope, len int, buf []byte) []byt
 				// RTC
 				retVal, err = getRTC(t, buf)
 			case 16, 17:
-				err = executeSlew(t, buf)
-				ret = "#"
+				retVal, err = n.motorCommand(t, buf)
 			default:
 				log.Errorf("unsupported P command: %c%c%c%c%c%c%c",
 					buf[0], buf[1], buf[2], buf[3], buf[4], buf[5], buf[6])
@@ -211,6 +311,28 @@ func (n *NexStar) nexstarCommand(t *alpaca.Telescope, len int, buf []byte) []byt
 		err = t.PutSlewToCoordinatestAsync(radec.RA, radec.Dec)
 		ret = "#"
 
+	case 'b':
+		// precise goto Azm/Alt values
+		altaz := NewAltAzNexstar(buf[1:9], buf[10:18], true)
+		err = slewToAltAz(t, altaz.Alt, altaz.Az, n.AutoTrack)
+		ret = "#"
+
+	case 'B':
+		// goto Azm/Alt values
+		altaz := NewAltAzNexstar(buf[1:5], buf[6:10], false)
+		err = slewToAltAz(t, altaz.Alt, altaz.Az, n.AutoTrack)
+		ret = "#"
+
+	case 'x':
+		// hibernate
+		err = park(t)
+		ret = "#"
+
+	case 'y':
+		// wake up from hibernate
+		err = unpark(t, n.AutoTrack)
+		ret = "#"
+
 	case 'w':
 		// get location
 		failed := false
@@ -308,8 +430,8 @@ func (n *NexStar) nexstarCommand(t *alpaca.Telescope, len int, buf []byte) []byt
 		}
 
 	case 'm':
-		// Model- hard code to 6/8 SE
-		retVal = []byte{12, '#'}
+		// Model
+		retVal = []byte{n.Profile.Model, '#'}
 
 	case 'M':
 		// cancel GOTO
@@ -329,55 +451,246 @@ func (n *NexStar) nexstarCommand(t *alpaca.Telescope, len int, buf []byte) []byt
 	if ret != "" {
 		retVal = []byte(ret)
 	}
+	// clients wait for the '#', so even failed commands must send it
+	if len(retVal) == 0 {
+		retVal = []byte{'#'}
+	}
 	return retVal
 }
 
 /*
- * So ASCOM and NexStar implement slewing a little differently.
+ * Alpaca only knows if the mount is tracking, so the NexStar tracking mode
+ * comes from the alignment mode and which hemisphere we are in.  Drivers
+ * which don't report them fall back to the --mount-type.
+ */
+func (n *NexStar) trackingMode(t *alpaca.Telescope) (alpaca.TrackingMode, error) {
+	tracking, err := t.GetTracking()
+	if err != nil || tracking == alpaca.NotTracking {
+		return alpaca.NotTracking, err
+	}
+
+	mode, err := t.GetAlignmentMode()
+	if err != nil {
+		log.Warnf("Unable to get alignment mode: %s", err.Error())
+		return t.Tracking, nil
+	}
+	if mode == alpaca.AlignmentAltAz {
+		return alpaca.AltAz, nil
+	}
+
+	lat, err := t.GetSiteLatitude()
+	if err != nil {
+		log.Warnf("Unable to get site latitude: %s", err.Error())
+		if t.Tracking == alpaca.EQSouth {
+			return alpaca.EQSouth, nil
+		}
+		return alpaca.EQNorth, nil
+	}
+	if lat < 0.0 {
+		return alpaca.EQSouth, nil
+	}
+	return alpaca.EQNorth, nil
+}
+
+/*
+ * Turns tracking on or off.  Alpaca can't change the alignment of the mount
+ * so any tracking mode other than off just enables tracking.
+ */
+func (n *NexStar) setTrackingMode(t *alpaca.Telescope, mode alpaca.TrackingMode) error {
+	if mode > alpaca.EQSouth {
+		return fmt.Errorf("invalid tracking mode: %d", mode)
+	}
+	canSet, err := t.GetCanSetTracking()
+	if err != nil {
+		return fmt.Errorf("unable to get cansettracking: %s", err.Error())
+	} else if !canSet {
+		return fmt.Errorf("mount does not support changing tracking")
+	}
+	if mode != alpaca.NotTracking {
+		if current, err := n.trackingMode(t); err == nil && current != alpaca.NotTracking && current != mode {
+			log.Warnf("Unable to change tracking mode from %d to %d", current, mode)
+		}
+	}
+	return t.PutTracking(mode)
+}
+
+/*
+ * Handles the 'P' commands for the Azm/RA (16) and Alt/Dec (17) motors:
  *
- * NexStar uses Azm/Alt, direction, speed (0, 2, 5, 7, 9).
- * ASCOM uses Azm/Alt + direction w/ speed as a function of -3 to +3
+ * P 3 axis 6/7 rateHigh rateLow 0 0     variable rate slew (arcsec/sec * 4)
+ * P 2 axis 36/37 rate 0 0 0             fixed rate slew (0-9)
+ * P 3 axis 38 rate duration 0 0         guide pulse (% sidereal, 1/100 sec)
+ * P 1 axis 39 0 0 0 1                   is a guide pulse active?
+ * P 2 axis 70 rate 0 0 0                set guide rate (sidereal * 256)
+ * P 1 axis 71 0 0 0 1                   get guide rate
+ * P 1 axis 25 0 0 0 0                   find home (seek index)
+ * P 1 axis 24 0 0 0 1                   is the mount home?
+ * P 1 16 56/57 0 0 0 0                  enable/disable cord wrap
+ * P 4 16 58 high mid low 0              set cord wrap position
+ * P 1 16 59 0 0 0 1                     is cord wrap enabled?
+ * P 1 16 60 0 0 0 3                     get cord wrap position
  *
- * In both cases, it will slew until a new slew command with a speed of 0 is
- * issued to stop the slew so we can handle this without any state.
+ * Alpaca has no cord wrap and the ASCOM driver picks which way the mount
+ * goes, so we can't enforce one.  Enabling cord wrap is refused, and it is
+ * always reported as disabled so the client knows.
  *
- * Note that NexStar supports both fixed & variable slew rates, however SkySafari
- * only uses the fixed type and ASCOM has no concept of variable rates so we will
- * treat variable as fixed.
+ * Slews continue until another slew with a rate of 0 just like ASCOM's
+ * MoveAxis, so we don't need any state.
  */
-func executeSlew(t *alpaca.Telescope, buf []byte) error {
+func (n *NexStar) motorCommand(t *alpaca.Telescope, buf []byte) ([]byte, error) {
 	axis := alpaca.AxisAzmRa
-	positiveDirection := false
-	var rate int // SkySafari uses direction with speeds of 0,2,5,7,9 but ASCOM uses axis with speeds -3 to 3
-
-	switch int(buf[2]) {
-	case 16:
-		// Azm/RA
-		axis = alpaca.AxisAzmRa
-	case 17:
-		// Alt/Dec
+	if int(buf[2]) == 17 {
 		axis = alpaca.AxisAltDec
-	default:
-		log.Errorf("unknown axis: %d", int(buf[2]))
 	}
 
 	switch int(buf[3]) {
-	case 6, 36:
-		positiveDirection = true
-	case 7, 37:
-		positiveDirection = false
-	default:
-		log.Errorf("unknown direction: %d", int(buf[3]))
+	case NEXSTAR_MC_MOVE_POS, NEXSTAR_MC_MOVE_NEG:
+		rate := float64(int(buf[4])<<8+int(buf[5])) / 4.0 / 3600.0
+		if int(buf[3]) == NEXSTAR_MC_MOVE_NEG {
+			rate *= -1
+		}
+		return []byte{'#'}, n.moveAxis(t, axis, rate)
+
+	case NEXSTAR_MC_FIXED_POS, NEXSTAR_MC_FIXED_NEG:
+		var rate float64
+		if buf[4] > 9 {
+			return []byte{'#'}, fmt.Errorf("invalid fixed slew rate: %d", buf[4])
+		} else if buf[4] > 0 {
+			rate = n.SlewRates[buf[4]-1]
+		}
+		if int(buf[3]) == NEXSTAR_MC_FIXED_NEG {
+			rate *= -1
+		}
+		return []byte{'#'}, n.moveAxis(t, axis, rate)
+
+	case NEXSTAR_MC_AUX_GUIDE:
+		return []byte{'#'}, n.pulseGuide(t, axis, int8(buf[4]), int(buf[5])*10)
+
+	case NEXSTAR_MC_AUX_GUIDE_ACTIVE:
+		guiding, err := n.isGuiding(t, axis)
+		if guiding {
+			return []byte{1, '#'}, err
+		}
+		return []byte{0, '#'}, err
+
+	case NEXSTAR_MC_SET_AUTOGUIDE_RATE:
+		canSet, err := t.GetCanSetGuideRates()
+		if err != nil {
+			return []byte{'#'}, err
+		} else if !canSet {
+			return []byte{'#'}, fmt.Errorf("mount does not support setting guide rates")
+		}
+		rate := float64(buf[4]) / 256.0 * SIDEREAL_RATE
+		if axis == alpaca.AxisAzmRa {
+			return []byte{'#'}, t.PutGuideRateRightAscension(rate)
+		}
+		return []byte{'#'}, t.PutGuideRateDeclination(rate)
+
+	case NEXSTAR_MC_SEEK_INDEX:
+		return []byte{'#'}, findHome(t)
+
+	case NEXSTAR_MC_SEEK_DONE:
+		atHome, err := t.GetAtHome()
+		if atHome {
+			return []byte{0xff, '#'}, err
+		}
+		return []byte{0, '#'}, err
+
+	case NEXSTAR_MC_ENABLE_CORDWRAP:
+		return []byte{'#'}, fmt.Errorf("cord wrap is not supported, it is up to the ASCOM driver")
+
+	case NEXSTAR_MC_DISABLE_CORDWRAP:
+		return []byte{'#'}, nil
+
+	case NEXSTAR_MC_SET_CORDWRAP_POS:
+		n.wrapLock.Lock()
+		n.wrapPos = GPSToDegrees(buf[4:7])
+		n.wrapLock.Unlock()
+		return []byte{'#'}, nil
+
+	case NEXSTAR_MC_POLL_CORDWRAP:
+		return []byte{0, '#'}, nil
+
+	case NEXSTAR_MC_GET_CORDWRAP_POS:
+		n.wrapLock.Lock()
+		defer n.wrapLock.Unlock()
+		return LatLongToGPS(n.wrapPos), nil
+
+	case NEXSTAR_MC_GET_AUTOGUIDE_RATE:
+		var rate float64
+		var err error
+		if axis == alpaca.AxisAzmRa {
+			rate, err = t.GetGuideRateRightAscension()
+		} else {
+			rate, err = t.GetGuideRateDeclination()
+		}
+		if err != nil {
+			return []byte{'#'}, err
+		}
+		return []byte{byte(math.Min(rate/SIDEREAL_RATE*256.0, 255.0)), '#'}, nil
 	}
 
-	rate = nextstartRateToASCOM(positiveDirection, int(buf[4]))
+	log.Errorf("unsupported motor command: %d", int(buf[3]))
+	return []byte{'#'}, nil
+}
 
-	// buf[1] is variable vs. fixed rate, but we always use fixed
-	// buf[5] is the "slow" variable rate which we always ignore
-	// Last two bytes (6, 7) are always 0
+// Moves the axis at the rate (deg/sec) limited to what the mount supports
+func (n *NexStar) moveAxis(t *alpaca.Telescope, axis alpaca.AxisType, rate float64) error {
+	n.rateLock.Lock()
+	limits, ok := n.maxRates[axis]
+	if !ok {
+		var err error
+		limits, err = t.GetAxisRates(axis)
+		if err != nil {
+			log.Errorf("Unable to query axis rates: %s", err.Error())
+		} else {
+			n.maxRates[axis] = limits
+		}
+	}
+	n.rateLock.Unlock()
 
-	err := t.PutMoveAxis(axis, rate)
-	return err
+	speed := math.Abs(rate)
+	if maxRate := limits["Maximum"]; maxRate > 0.0 && speed > maxRate {
+		speed = maxRate
+	} else if minRate := limits["Minimum"]; speed > 0.0 && speed < minRate {
+		speed = minRate
+	}
+	return t.PutMoveAxis(axis, math.Copysign(speed, rate))
+}
+
+/*
+ * Sends a guide pulse at the rate (% of sidereal, positive is North/West)
+ * for ms.  Mounts which can't pulse guide are moved at the rate instead.
+ */
+func (n *NexStar) pulseGuide(t *alpaca.Telescope, axis alpaca.AxisType, rate int8, ms int) error {
+	canPulse, err := t.GetCanPulseGuide()
+	if err != nil {
+		log.Warnf("Unable to get canpulseguide: %s", err.Error())
+	}
+	if canPulse {
+		// same directions as LX200 :Mg
+		var direction alpaca.GuideDirection
+		switch {
+		case axis == alpaca.AxisAzmRa && rate >= 0:
+			direction = alpaca.GuideWest
+		case axis == alpaca.AxisAzmRa:
+			direction = alpaca.GuideEast
+		case rate >= 0:
+			direction = alpaca.GuideNorth
+		default:
+			direction = alpaca.GuideSouth
+		}
+		return t.PutPulseGuide(direction, ms)
+	}
+	return n.guider.pulse(t, axis, float64(rate)/100.0*SIDEREAL_RATE, ms)
+}
+
+// Is the axis being pulse guided?
+func (n *NexStar) isGuiding(t *alpaca.Telescope, axis alpaca.AxisType) (bool, error) {
+	if n.guider.guiding(axis) {
+		return true, nil
+	}
+	return t.GetIsPulseGuiding()
 }
 
 /*
@@ -392,8 +705,9 @@ func executeSlew(t *alpaca.Telescope, buf []byte) error {
  */
 func getGPS(t *alpaca.Telescope, buf []byte) ([]byte, error) {
 	retVal := []byte{}
-	switch int(buf[2]) {
-	case 55:
+	switch int(buf[3]) {
+	case 54, 55:
+		// Is the time valid (54) or the GPS linked (55)?  Both come from the mount
 		_, err := t.GetSiteLatitude()
 		if err != nil {
 			// GPS is not linked
@@ -444,50 +758,82 @@ func getGPS(t *alpaca.Telescope, buf []byte) ([]byte, error) {
 			log.Errorf("GPS returned no UTC date: %s", err.Error())
 			return retVal, err
 		}
-		h, m, s := utcDate.Date()
+		h, m, s := utcDate.Clock()
 		retVal = []byte{byte(h), byte(m), byte(s), '#'}
+	default:
+		log.Errorf("unsupported GPS P command: %d", int(buf[3]))
+		retVal = []byte{'#'}
 	}
 	return retVal, nil
 }
 
 /*
- * getRTC is another 'P' command which returns date from the real time clock
- * in the mount.  Note that the time values from these commands are supposed
- * to come from the RTC and not GPS or hand controller, but ASCOM doesn't treat
- * them differently since it is up to the driver.
+ * getRTC is another 'P' command which gets or sets the date from the real
+ * time clock in the mount.  Note that the time values from these commands
+ * are supposed to come from the RTC and not GPS or hand controller, but
+ * ASCOM doesn't treat them differently since it is up to the driver.
  *
  * RTC is v1.6+ for get and v3.01+ for set.
  */
 func getRTC(t *alpaca.Telescope, buf []byte) ([]byte, error) {
-	switch int(buf[2]) {
+	switch int(buf[3]) {
 	case 3, 4, 51:
 		// These commands to get date, time and year are the same
 		// as the GPS commands, so reuse that code
 		return getGPS(t, buf)
+	case 131, 132, 179:
+		return []byte{'#'}, setRTC(t, buf)
 	default:
 		log.Errorf("unsupported RTC P command: %c%c%c%c%c%c%c",
 			buf[0], buf[1], buf[2], buf[3], buf[4], buf[5], buf[6])
 	}
-	return []byte{}, nil
+	return []byte{'#'}, nil
 }
 
-// Converts the direction & rate to an ASCOM rate
-func nextstartRateToASCOM(direction bool, rate int) int {
-	switch rate {
-	case 0:
-		rate = 0
-	case 1, 2, 3:
-		rate = 1
-	case 4, 5, 6:
-		rate = 2
-	case 7, 8, 9:
-		rate = 3
+// Returns the number of days in the month
+func daysIn(year int, month time.Month) int {
+	// day 0 of the next month is the last day of this month
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+/*
+ * Sets the month & day (131), year (132) or time (179) of the mount's
+ * UTC date.  Alpaca sets the whole date at once, so the rest comes from
+ * the mount.
+ */
+func setRTC(t *alpaca.Telescope, buf []byte) error {
+	utcDate, err := t.GetUTCDate()
+	if err != nil {
+		return fmt.Errorf("unable to get UTC date: %s", err.Error())
 	}
-	// flip our direction?
-	if !direction {
-		rate *= -1
+	year, month, day := utcDate.Date()
+	h, m, s := utcDate.Clock()
+
+	switch int(buf[3]) {
+	case 131:
+		// Date: m, d
+		month, day = time.Month(buf[4]), int(buf[5])
+		if month < time.January || month > time.December || day < 1 || day > daysIn(year, month) {
+			return fmt.Errorf("invalid RTC date: %d/%d", month, day)
+		}
+	case 132:
+		// Year: (x * 256) + y = year
+		year = int(buf[4])*256 + int(buf[5])
+		if day > daysIn(year, month) {
+			// Feb 29th in a non-leap year
+			return fmt.Errorf("invalid RTC year for %d/%d: %d", month, day, year)
+		}
+	case 179:
+		// Time: h, m, s
+		h, m, s = int(buf[4]), int(buf[5]), int(buf[6])
+		if h > 23 || m > 59 || s > 59 {
+			return fmt.Errorf("invalid RTC time: %d:%d:%d", h, m, s)
+		}
 	}
-	return rate
+
+	date := time.Date(year, month, day, h, m, s, 0, time.UTC)
+	log.Debugf("client set RTC to: %s", date.String())
+	return t.PutUTCDate(date)
 }
 
 // convert ABCDEFGH bytes to lat/long
@@ -505,32 +851,19 @@ func NexstarToLatLong(b []byte) (float64, float64) {
 
 // Convert Lat/Long to ABCDEFGH bytes for hand controller
 func LatLongToNexstar(lat float64, long float64) []byte {
-	var a, b, c, d, e, f, g, h byte
+	format := SexagesimalFormat{Seconds: true}
+	south, a, b, c := format.split(lat)
+	west, e, f, g := format.split(long)
+
 	// West & South are negative
-	if lat < 0 {
+	var d, h byte
+	if south {
 		d = 1
-		lat = math.Abs(lat)
 	}
-	if long < 0 {
+	if west {
 		h = 1
-		long = math.Abs(long)
 	}
-
-	// latitude
-	a = byte(int(lat))
-	remain := lat - math.Floor(lat)
-	b = byte(int(remain * 60.0))
-	fracMinute := remain - float64(b)/60.0
-	c = byte(int(fracMinute * 60.0 * 60.0))
-
-	// longitude
-	e = byte(int(long))
-	remain = long - math.Floor(long)
-	f = byte(int(remain * 60.0))
-	fracMinute = remain - float64(f)/60.0
-	g = byte(int(fracMinute * 60.0 * 60.0))
-
-	return []byte{a, b, c, d, e, f, g, h}
+	return []byte{byte(a), byte(b), byte(c), d, byte(e), byte(f), byte(g), h}
 }
 
 /*
@@ -547,7 +880,7 @@ func LatLongToGPS(latlong float64) []byte {
 		latlong += 360.0
 	}
 
-	position := uint32(latlong * float64(2^24) / 360.0)
+	position := uint32(latlong * float64(1<<24) / 360.0)
 	pos[0] = byte(position & 0x00ff0000 >> 16)
 	pos[1] = byte(position & 0x0000ff00 >> 8)
 	pos[2] = byte(position & 0x000000ff)
@@ -555,10 +888,13 @@ func LatLongToGPS(latlong float64) []byte {
 	return pos
 }
 
-/*
- * Nexstar supports querying in RA/Dec as well as Alt/Azm, but goto only
- * works in RA/Dec mode, so we don't support Alt/Azm
- */
+// Converts the GPS "XYZ" fraction of a rotation to degrees 0-360
+func GPSToDegrees(b []byte) float64 {
+	position := uint32(b[0])<<16 + uint32(b[1])<<8 + uint32(b[2])
+	return float64(position) / float64(1<<24) * 360.0
+}
+
+// Converts the ASCII RA/Dec bytes from the 'r', 'R', 's' & 'S' commands
 func NewCoordinateNexstar(raBytes []byte, decBytes []byte, highp bool) Coordinates {
 	var ra, dec float64
 	if highp {
@@ -594,6 +930,33 @@ func (c *Coordinates) Nexstar(highp bool) string {
 	return fmt.Sprintf(fmtstr, ra, dec)
 }
 
+/*
+ * Converts the ASCII Azm/Alt bytes from the 'b' & 'B' commands.  Like Dec,
+ * negative altitudes are sent as 360 + alt.
+ */
+func NewAltAzNexstar(azmBytes []byte, altBytes []byte, highp bool) AltAz {
+	var azm, alt float64
+	if highp {
+		azm = uint32StepsToAzm(StepsToUint32(azmBytes))
+		alt = uint32StepsToDec(StepsToUint32(altBytes))
+	} else {
+		azm = uint16StepsToAzm(StepsToUint16(azmBytes))
+		alt = uint16StepsToDec(StepsToUint16(altBytes))
+	}
+	return AltAz{
+		Alt: alt,
+		Az:  azm,
+	}
+}
+
+// Converts our Azm/Alt to an ASCII string format for Nexstar
+func (a *AltAz) Nexstar(highp bool) string {
+	if !highp {
+		return fmt.Sprintf("%04X,%04X", azmTo16bitSteps(a.Az), decTo16bitSteps(a.Alt))
+	}
+	return fmt.Sprintf("%08X,%08X", azmTo32bitSteps(a.Az), decTo32bitSteps(a.Alt))
+}
+
 /*
  * Functions to convert the Nexstar ASCII RA/DEC & ALT/AZM
  * steps to uint32/16
@@ -665,3 +1028,20 @@ func decTo16bitSteps(dec float64) uint16 {
 		return uint16(dec / 360.0 * math.Pow(2, 16))
 	}
 }
+
+func uint32StepsToAzm(steps uint32) float64 {
+	return float64(steps) / math.Pow(2, 32) * 360.0
+}
+
+func uint16StepsToAzm(steps uint16) float64 {
+	return float64(steps) / math.Pow(2, 16) * 360.0
+}
+
+// azimuths of 360 and above wrap around to 0
+func azmTo32bitSteps(azm float64) uint32 {
+	return uint32(math.Pow(2, 32) * math.Mod(azm, 360.0) / 360.0)
+}
+
+func azmTo16bitSteps(azm float64) uint16 {
+	return uint16(math.Pow(2, 16) * math.Mod(azm, 360.0) / 360.0)
+}
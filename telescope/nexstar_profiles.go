@@ -0,0 +1,135 @@
+package telescope
+
+/*
+ * NexStar emulation profiles.  Clients identify the hand controller via the
+ * 'V', 'v' and 'm' commands and the 'P' device versions and only use the
+ * commands that version supports, so each profile defines the identity and
+ * command set of one hand controller.
+ *
+ * Models are from the Celestron NexStar communication protocol document,
+ * except for SynScan which uses the Sky-Watcher mount codes.
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	DEFAULT_NEXSTAR_PROFILE = "nexstar-plus"
+)
+
+// 'v' hand controller variants
+const (
+	NEXSTAR_VARIANT_NEXSTAR   = 0x11
+	NEXSTAR_VARIANT_STARSENSE = 0x13
+)
+
+type NexStarProfile struct {
+	Name           string // CLI/config name
+	Description    string
+	Version        []byte         // 'V' major, minor (& patch for SynScan)
+	VersionHex     bool           // 'V' is ASCII hex like SynScan instead of binary
+	Variant        byte           // 'v', 0 if not supported
+	Model          byte           // 'm'
+	Unsupported    string         // commands the hand controller doesn't have
+	DeviceVersions map[int][]byte // 'P' devices and their versions
+}
+
+var (
+	// Celestron motor controllers, GPS & RTC
+	celestronDevices = map[int][]byte{
+		16:  {5, 0},
+		17:  {5, 0},
+		176: {1, 6},
+		178: {1, 6},
+	}
+
+	nexstarProfiles = map[string]NexStarProfile{
+		"nexstar-plus": {
+			Name:           "nexstar-plus",
+			Description:    "Celestron NexStar+ hand controller (6/8 SE)",
+			Version:        []byte{5, 34},
+			Variant:        NEXSTAR_VARIANT_NEXSTAR,
+			Model:          12,
+			DeviceVersions: celestronDevices,
+		},
+		"starsense": {
+			Name:           "starsense",
+			Description:    "Celestron StarSense hand controller (AVX)",
+			Version:        []byte{1, 19},
+			Variant:        NEXSTAR_VARIANT_STARSENSE,
+			Model:          20,
+			DeviceVersions: celestronDevices,
+		},
+		"cgx": {
+			Name:           "cgx",
+			Description:    "Celestron CGX with a NexStar+ hand controller",
+			Version:        []byte{5, 34},
+			Variant:        NEXSTAR_VARIANT_NEXSTAR,
+			Model:          23,
+			DeviceVersions: celestronDevices,
+		},
+		"evolution": {
+			Name:           "evolution",
+			Description:    "Celestron NexStar Evolution",
+			Version:        []byte{5, 34},
+			Variant:        NEXSTAR_VARIANT_NEXSTAR,
+			Model:          22,
+			DeviceVersions: celestronDevices,
+		},
+		"synscan": {
+			Name:        "synscan",
+			Description: "Sky-Watcher SynScan hand controller (EQ6) in NexStar mode",
+			Version:     []byte{4, 39, 5},
+			VersionHex:  true,
+			Model:       0,
+			// no hibernate or variant and only the motors via 'P'
+			Unsupported: "xyv",
+			DeviceVersions: map[int][]byte{
+				16: {5, 0},
+				17: {5, 0},
+			},
+		},
+	}
+)
+
+// Returns the named profile
+func GetNexStarProfile(name string) (NexStarProfile, error) {
+	p, ok := nexstarProfiles[name]
+	if !ok {
+		return NexStarProfile{}, fmt.Errorf("unknown NexStar profile: %s", name)
+	}
+	return p, nil
+}
+
+// Returns the names of all the profiles
+func NexStarProfileNames() []string {
+	names := []string{}
+	for name := range nexstarProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Does the hand controller support the command?
+func (p NexStarProfile) Supports(cmd byte) bool {
+	if cmd == 'v' && p.Variant == 0 {
+		return false
+	}
+	return strings.IndexByte(p.Unsupported, cmd) < 0
+}
+
+// Returns the 'V' reply
+func (p NexStarProfile) VersionReply() []byte {
+	if !p.VersionHex {
+		return append(append([]byte{}, p.Version...), '#')
+	}
+	reply := ""
+	for _, b := range p.Version {
+		reply += fmt.Sprintf("%02X", b)
+	}
+	return []byte(reply + "#")
+}
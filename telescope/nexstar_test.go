@@ -2,9 +2,12 @@ package telescope
 
 import (
 	"math"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/synfinatic/alpacascope/alpaca"
 )
 
 type LatLong struct {
@@ -34,6 +37,9 @@ func TestLatLong(t *testing.T) {
 		cbytes := LatLongToNexstar(test.Lat, test.Long)
 		assert.Equal(t, test.Bytes, cbytes)
 	}
+
+	// seconds are rounded, not truncated
+	assert.Equal(t, []byte{41, 0, 0, 0, 122, 30, 0, 1}, LatLongToNexstar(40.9999999, -122.4999999))
 }
 
 func TestNexstarRA32(t *testing.T) {
@@ -143,12 +149,12 @@ func TestNexstarDec16(t *testing.T) {
 func TestNestarToHMS(t *testing.T) {
 	tests := map[uint32]HMS{
 		0:                                NewHMS(0, 0, 0.0),
-		1:                                NewHMS(0, 0, 3.3527612686157227e-07),
+		1:                                NewHMS(0, 0, 2.0116567611694336e-05),
 		uint32(math.Pow(2, 32) / 2.0):    NewHMS(12.0, 0, 0.0),
-		uint32(math.Pow(2, 32)/2.0 + 1):  NewHMS(12.0, 0, 3.3527612686157227e-07),
-		uint32(math.Pow(2, 32)/2.0 + 2):  NewHMS(12.0, 0, 6.705522537231445e-07),
-		uint32(math.Pow(2, 32) / 24.0):   NewHMS(0.0, 59, 0.9999997764825852),
-		uint32(math.Pow(2, 32)/24.0 + 1): NewHMS(1.0, 0, 1.1175870895385742e-07),
+		uint32(math.Pow(2, 32)/2.0 + 1):  NewHMS(12.0, 0, 2.0116567611694336e-05),
+		uint32(math.Pow(2, 32)/2.0 + 2):  NewHMS(12.0, 0, 4.023313522338867e-05),
+		uint32(math.Pow(2, 32) / 24.0):   NewHMS(0.0, 59, 59.99998658895511),
+		uint32(math.Pow(2, 32)/24.0 + 1): NewHMS(1.0, 0, 6.705522537231445e-06),
 	}
 	for input, check := range tests {
 		raValue := uint32StepsToRA(input)
@@ -159,7 +165,7 @@ func TestNestarToHMS(t *testing.T) {
 		hms := c.RAToHMS()
 		assert.Equal(t, check.Hours, hms.Hours)
 		assert.Equal(t, check.Minutes, hms.Minutes)
-		assert.Equal(t, check.Seconds, hms.Seconds)
+		assert.InDelta(t, check.Seconds, hms.Seconds, 1e-9)
 	}
 }
 
@@ -195,3 +201,477 @@ func TestNexstarDecBytes(t *testing.T) {
 		assert.Equal(t, dec, c.Dec)
 	}
 }
+
+func nexstarReply(n *NexStar, scope *alpaca.Telescope, cmd string) string {
+	return string(n.nexstarCommand(scope, len(cmd), []byte(cmd)))
+}
+
+// Examples from the Celestron NexStar communication protocol document
+func TestNexstarAltAzBytes(t *testing.T) {
+	altaz := NewAltAzNexstar([]byte("12AB"), []byte("4000"), false)
+	assert.Equal(t, 26.2518310546875, altaz.Az)
+	assert.Equal(t, 90.0, altaz.Alt)
+	assert.Equal(t, "12AB,4000", altaz.Nexstar(false))
+
+	altaz = NewAltAzNexstar([]byte("12AB0500"), []byte("40000500"), true)
+	assert.InDelta(t, 26.2518310546875, altaz.Az, 1e-3)
+	assert.InDelta(t, 90.0, altaz.Alt, 1e-3)
+	assert.Equal(t, "12AB0500,40000500", altaz.Nexstar(true))
+
+	// negative altitudes are 360 + alt
+	altaz = NewAltAzNexstar([]byte("0000"), []byte("F8E3"), false)
+	assert.InDelta(t, -10.0, altaz.Alt, 0.01)
+	altaz = AltAz{Alt: -10.0, Az: 360.0}
+	assert.Equal(t, "0000,F8E3", altaz.Nexstar(false))
+	assert.Equal(t, "00000000,F8E38E38", altaz.Nexstar(true))
+}
+
+func TestNexstarGetAltAz(t *testing.T) {
+	scope, _ := newMockTelescope(t, map[string]interface{}{
+		"azimuth":  180.0,
+		"altitude": -10.0,
+	})
+	n := NewNexStar(true)
+	assert.Equal(t, "8000,F8E3#", nexstarReply(n, scope, "Z"))
+	assert.Equal(t, "80000000,F8E38E38#", nexstarReply(n, scope, "z"))
+}
+
+func TestNexstarGotoAltAz(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"canslewaltazasync": true,
+		"sitelatitude":      40.0,
+		"sitelongitude":     -75.0,
+		"utcdate":           "2021-03-20T04:00:00Z",
+	})
+	n := NewNexStar(true)
+
+	assert.Equal(t, "#", nexstarReply(n, scope, "B12AB,4000"))
+	puts := m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "slewtoaltazasync", puts[0].API)
+	assert.Equal(t, "26.2518310546875", puts[0].Form.Get("Azimuth"))
+	assert.Equal(t, "90", puts[0].Form.Get("Altitude"))
+
+	// mounts which can't slew to alt/az get the RA/Dec
+	m.Set("canslewaltazasync", false)
+	assert.Equal(t, "#", nexstarReply(n, scope, "b80000000,1C71C71C"))
+	puts = m.Puts()
+	assert.Equal(t, "tracking", puts[0].API) // auto-track
+	slew := puts[len(puts)-1]
+	assert.Equal(t, "slewtocoordinatesasync", slew.API)
+
+	now, _ := time.Parse(time.RFC3339, "2021-03-20T04:00:00Z")
+	ra, _ := strconv.ParseFloat(slew.Form.Get("RightAscension"), 64)
+	dec, _ := strconv.ParseFloat(slew.Form.Get("Declination"), 64)
+	assert.InDelta(t, LocalSiderealTime(now, -75.0), ra, 1e-6) // on the meridian
+	assert.InDelta(t, -10.0, dec, 1e-6)
+}
+
+func TestParseNexStarSlewRates(t *testing.T) {
+	rates, err := ParseNexStarSlewRates(NEXSTAR_SLEW_RATES)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.008, rates[0])
+	assert.Equal(t, 4.0, rates[8])
+
+	_, err = ParseNexStarSlewRates("1 2 3 4 5 6 7 8")
+	assert.Error(t, err)
+	_, err = ParseNexStarSlewRates("1 2 3 4 5 6 7 8 x")
+	assert.Error(t, err)
+	_, err = ParseNexStarSlewRates("1 2 3 4 5 6 7 8 -9")
+	assert.Error(t, err)
+}
+
+func TestNexstarSlew(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"axisrates": []map[string]float64{{"Minimum": 0.001, "Maximum": 3.0}},
+	})
+	n := NewNexStar(true)
+
+	tests := []struct {
+		Cmd  []byte
+		Axis string
+		Rate string
+	}{
+		// fixed rates from the table
+		{[]byte{'P', 2, 16, 36, 9, 0, 0, 0}, "0", "3"}, // limited by the mount
+		{[]byte{'P', 2, 16, 37, 7, 0, 0, 0}, "0", "-1"},
+		{[]byte{'P', 2, 17, 36, 1, 0, 0, 0}, "1", "0.008"},
+		{[]byte{'P', 2, 17, 37, 0, 0, 0, 0}, "1", "0"},
+		// variable rates are arcsec/sec * 4
+		{[]byte{'P', 3, 16, 6, 0x0e, 0x10, 0, 0}, "0", "0.25"},        // 900"/sec
+		{[]byte{'P', 3, 17, 7, 0x00, 0x3c, 0, 0}, "1", "-0.00416667"}, // 15"/sec
+		{[]byte{'P', 3, 17, 6, 0x00, 0x01, 0, 0}, "1", "0.001"},       // below the minimum
+		{[]byte{'P', 3, 16, 7, 0xff, 0xff, 0, 0}, "0", "-3"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, "#", string(n.nexstarCommand(scope, len(test.Cmd), test.Cmd)), test.Cmd)
+		puts := m.Puts()
+		assert.Len(t, puts, 1, test.Cmd)
+		assert.Equal(t, "moveaxis", puts[0].API, test.Cmd)
+		assert.Equal(t, test.Axis, puts[0].Form.Get("Axis"), test.Cmd)
+		rate, _ := strconv.ParseFloat(puts[0].Form.Get("Rate"), 64)
+		expected, _ := strconv.ParseFloat(test.Rate, 64)
+		assert.InDelta(t, expected, rate, 1e-6, test.Cmd)
+	}
+
+	n.SlewRates, _ = ParseNexStarSlewRates("0.1 0.2 0.3 0.4 0.5 0.6 0.7 0.8 0.9")
+	cmd := []byte{'P', 2, 16, 36, 5, 0, 0, 0}
+	n.nexstarCommand(scope, len(cmd), cmd)
+	assert.Equal(t, "0.5", m.Puts()[0].Form.Get("Rate"))
+}
+
+func TestNexstarGuide(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"canpulseguide":           true,
+		"ispulseguiding":          true,
+		"cansetguiderates":        true,
+		"guideraterightascension": SIDEREAL_RATE / 2.0,
+	})
+	n := NewNexStar(true)
+
+	// P 3 axis 38 rate(% sidereal) duration(1/100 sec)
+	tests := []struct {
+		Cmd       []byte
+		Direction string
+	}{
+		{[]byte{'P', 3, 16, 38, 50, 100, 0, 0}, "3"},             // West
+		{[]byte{'P', 3, 16, 38, byte(256 - 50), 100, 0, 0}, "2"}, // East
+		{[]byte{'P', 3, 17, 38, 50, 100, 0, 0}, "0"},             // North
+		{[]byte{'P', 3, 17, 38, byte(256 - 50), 100, 0, 0}, "1"}, // South
+	}
+	for _, test := range tests {
+		assert.Equal(t, "#", string(n.nexstarCommand(scope, len(test.Cmd), test.Cmd)))
+		puts := m.Puts()
+		assert.Len(t, puts, 1)
+		assert.Equal(t, "pulseguide", puts[0].API)
+		assert.Equal(t, test.Direction, puts[0].Form.Get("Direction"))
+		assert.Equal(t, "1000", puts[0].Form.Get("Duration"))
+	}
+
+	cmd := []byte{'P', 1, 16, 39, 0, 0, 0, 1}
+	assert.Equal(t, []byte{1, '#'}, n.nexstarCommand(scope, len(cmd), cmd))
+	m.Set("ispulseguiding", false)
+	assert.Equal(t, []byte{0, '#'}, n.nexstarCommand(scope, len(cmd), cmd))
+
+	// guide rates are a fraction of sidereal * 256
+	cmd = []byte{'P', 1, 16, 71, 0, 0, 0, 1}
+	assert.Equal(t, []byte{128, '#'}, n.nexstarCommand(scope, len(cmd), cmd))
+	cmd = []byte{'P', 2, 17, 70, 64, 0, 0, 0}
+	assert.Equal(t, []byte{'#'}, n.nexstarCommand(scope, len(cmd), cmd))
+	puts := m.Puts()
+	assert.Equal(t, "guideratedeclination", puts[0].API)
+	rate, _ := strconv.ParseFloat(puts[0].Form.Get("GuideRateDeclination"), 64)
+	assert.InDelta(t, SIDEREAL_RATE/4.0, rate, 1e-9)
+
+	// never sent to mounts which can't set them
+	m.Set("cansetguiderates", false)
+	assert.Equal(t, []byte{'#'}, n.nexstarCommand(scope, len(cmd), cmd))
+	assert.Empty(t, m.Puts())
+
+	// mounts which can't pulse guide move at the rate instead
+	m.Set("canpulseguide", false)
+	cmd = []byte{'P', 3, 17, 38, byte(256 - 50), 5, 0, 0}
+	assert.Equal(t, "#", string(n.nexstarCommand(scope, len(cmd), cmd)))
+	cmd = []byte{'P', 1, 17, 39, 0, 0, 0, 1}
+	assert.Equal(t, []byte{1, '#'}, n.nexstarCommand(scope, len(cmd), cmd))
+	puts = m.Puts()
+	assert.Equal(t, "moveaxis", puts[0].API)
+	rate, _ = strconv.ParseFloat(puts[0].Form.Get("Rate"), 64)
+	assert.InDelta(t, -SIDEREAL_RATE/2.0, rate, 1e-6)
+	assert.Eventually(t, func() bool {
+		puts := m.Puts()
+		return len(puts) == 1 && puts[0].Form.Get("Rate") == "0"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestNexstarTrackingMode(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"tracking":       false,
+		"cansettracking": true,
+		"alignmentmode":  int(alpaca.AlignmentGermanPolar),
+		"sitelatitude":   40.0,
+	})
+	n := NewNexStar(true)
+
+	// replies are binary 0-3
+	assert.Equal(t, []byte{0, '#'}, []byte(nexstarReply(n, scope, "t")))
+	m.Set("tracking", true)
+	assert.Equal(t, []byte{2, '#'}, []byte(nexstarReply(n, scope, "t")))
+	m.Set("sitelatitude", -33.9)
+	assert.Equal(t, []byte{3, '#'}, []byte(nexstarReply(n, scope, "t")))
+	m.Set("alignmentmode", int(alpaca.AlignmentAltAz))
+	assert.Equal(t, []byte{1, '#'}, []byte(nexstarReply(n, scope, "t")))
+
+	// drivers without an alignment mode use the mount type
+	m.Set("alignmentmode", "unknown")
+	scope.Tracking = alpaca.EQNorth
+	assert.Equal(t, []byte{2, '#'}, []byte(nexstarReply(n, scope, "t")))
+
+	assert.Equal(t, "#", nexstarReply(n, scope, "T\x00"))
+	puts := m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "false", puts[0].Form.Get("Tracking"))
+	assert.Equal(t, []byte{0, '#'}, []byte(nexstarReply(n, scope, "t")))
+
+	assert.Equal(t, "#", nexstarReply(n, scope, "T\x02"))
+	puts = m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "true", puts[0].Form.Get("Tracking"))
+
+	// invalid modes and mounts which can't change tracking
+	assert.Equal(t, "#", nexstarReply(n, scope, "T\x04"))
+	assert.Empty(t, m.Puts())
+	m.Set("cansettracking", false)
+	assert.Equal(t, "#", nexstarReply(n, scope, "T\x00"))
+	assert.Empty(t, m.Puts())
+}
+
+func TestLatLongToGPS(t *testing.T) {
+	// (x * 65536 + y * 256 + z) / 2^24 * 360
+	assert.Equal(t, []byte{0x1c, 0x71, 0xc7, '#'}, LatLongToGPS(40.0))
+	assert.Equal(t, []byte{0xca, 0xaa, 0xaa, '#'}, LatLongToGPS(-75.0))
+	assert.Equal(t, []byte{0x40, 0x00, 0x00, '#'}, LatLongToGPS(90.0))
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, '#'}, LatLongToGPS(0.0))
+}
+
+func TestNexstarGPS(t *testing.T) {
+	scope, _ := newMockTelescope(t, map[string]interface{}{
+		"sitelatitude":  40.0,
+		"sitelongitude": -75.0,
+		"utcdate":       "2021-03-20T04:05:06Z",
+	})
+	n := NewNexStar(true)
+
+	tests := []struct {
+		Cmd   []byte
+		Reply []byte
+	}{
+		{[]byte{'P', 1, 176, 254, 0, 0, 0, 2}, []byte{1, 6, '#'}},           // version
+		{[]byte{'P', 1, 176, 54, 0, 0, 0, 1}, []byte{1, '#'}},               // time valid
+		{[]byte{'P', 1, 176, 55, 0, 0, 0, 1}, []byte{1, '#'}},               // linked
+		{[]byte{'P', 1, 176, 1, 0, 0, 0, 3}, []byte{0x1c, 0x71, 0xc7, '#'}}, // latitude
+		{[]byte{'P', 1, 176, 2, 0, 0, 0, 3}, []byte{0xca, 0xaa, 0xaa, '#'}}, // longitude
+		{[]byte{'P', 1, 176, 3, 0, 0, 0, 2}, []byte{3, 20, '#'}},            // date
+		{[]byte{'P', 1, 176, 4, 0, 0, 0, 2}, []byte{7, 229, '#'}},           // year
+		{[]byte{'P', 1, 176, 51, 0, 0, 0, 3}, []byte{4, 5, 6, '#'}},         // time
+		{[]byte{'P', 1, 178, 3, 0, 0, 0, 2}, []byte{3, 20, '#'}},            // RTC date
+		{[]byte{'P', 1, 178, 4, 0, 0, 0, 2}, []byte{7, 229, '#'}},           // RTC year
+		{[]byte{'P', 1, 178, 51, 0, 0, 0, 3}, []byte{4, 5, 6, '#'}},         // RTC time
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.Reply, n.nexstarCommand(scope, len(test.Cmd), test.Cmd), test.Cmd)
+	}
+
+	// no GPS when we can't talk to the mount
+	scope, m := newMockTelescope(t, map[string]interface{}{})
+	m.server.Close()
+	for _, cmd := range [][]byte{
+		{'P', 1, 176, 54, 0, 0, 0, 1},
+		{'P', 1, 176, 55, 0, 0, 0, 1},
+	} {
+		assert.Equal(t, []byte{0, '#'}, n.nexstarCommand(scope, len(cmd), cmd), cmd)
+	}
+}
+
+func TestNexstarSetRTC(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"utcdate": "2021-03-20T04:05:06Z",
+	})
+	n := NewNexStar(true)
+
+	tests := []struct {
+		Cmd  []byte
+		Date string
+	}{
+		{[]byte{'P', 3, 178, 131, 12, 31, 0, 0}, "2021-12-31T04:05:06Z"},  // date
+		{[]byte{'P', 3, 178, 132, 7, 232, 0, 0}, "2024-12-31T04:05:06Z"},  // year
+		{[]byte{'P', 4, 178, 179, 23, 59, 58, 0}, "2024-12-31T23:59:58Z"}, // time
+	}
+	for _, test := range tests {
+		assert.Equal(t, []byte{'#'}, n.nexstarCommand(scope, len(test.Cmd), test.Cmd), test.Cmd)
+		puts := m.Puts()
+		assert.Len(t, puts, 1)
+		assert.Equal(t, test.Date, puts[0].Form.Get("UTCDate"))
+	}
+
+	// invalid dates & times aren't sent
+	for _, cmd := range [][]byte{
+		{'P', 3, 178, 131, 13, 1, 0, 0},
+		{'P', 3, 178, 131, 1, 0, 0, 0},
+		{'P', 3, 178, 131, 2, 30, 0, 0},
+		{'P', 3, 178, 131, 4, 31, 0, 0},
+		{'P', 4, 178, 179, 24, 0, 0, 0},
+	} {
+		assert.Equal(t, []byte{'#'}, n.nexstarCommand(scope, len(cmd), cmd), cmd)
+		assert.Empty(t, m.Puts())
+	}
+
+	// Feb 29th only exists in leap years
+	leapTests := []struct {
+		Date string
+		Cmd  []byte
+	}{
+		{"2021-03-20T04:05:06Z", []byte{'P', 3, 178, 131, 2, 29, 0, 0}},  // date
+		{"2024-02-29T04:05:06Z", []byte{'P', 3, 178, 132, 7, 229, 0, 0}}, // year
+	}
+	for _, test := range leapTests {
+		scope, m := newMockTelescope(t, map[string]interface{}{
+			"utcdate": test.Date,
+		})
+		assert.Equal(t, []byte{'#'}, n.nexstarCommand(scope, len(test.Cmd), test.Cmd), test.Cmd)
+		assert.Empty(t, m.Puts())
+	}
+}
+
+// clients wait for a '#' even when the mount returns an error
+func TestNexstarErrorReplies(t *testing.T) {
+	failed := mockError{Number: 0x400, Message: "not implemented"}
+	scope, _ := newMockTelescope(t, map[string]interface{}{
+		"get:utcdate":                 failed,
+		"get:tracking":                failed,
+		"get:guideraterightascension": failed,
+		"get:rightascension":          failed,
+		"get:sitelatitude":            failed,
+		"get:sitelongitude":           failed,
+		"get:azimuth":                 failed,
+	})
+	n := NewNexStar(true)
+
+	for _, cmd := range [][]byte{
+		{'t'},
+		{'e'},
+		{'z'},
+		{'w'},
+		{'h'},
+		{'P', 1, 16, 71, 0, 0, 0, 1},   // guide rate
+		{'P', 1, 176, 1, 0, 0, 0, 3},   // GPS latitude
+		{'P', 1, 178, 3, 0, 0, 0, 2},   // RTC date
+		{'P', 1, 178, 200, 0, 0, 0, 0}, // unknown RTC command
+		{'P', 3, 178, 131, 1, 1, 0, 0}, // set RTC date
+	} {
+		reply := n.nexstarCommand(scope, len(cmd), cmd)
+		if assert.NotEmpty(t, reply, cmd) {
+			assert.Equal(t, byte('#'), reply[len(reply)-1], cmd)
+		}
+	}
+}
+
+func TestNexstarHibernate(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"canpark":   true,
+		"canunpark": true,
+		"atpark":    true,
+		"tracking":  false,
+	})
+	n := NewNexStar(true)
+
+	assert.Equal(t, "#", nexstarReply(n, scope, "x"))
+	puts := m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "park", puts[0].API)
+
+	assert.Equal(t, "#", nexstarReply(n, scope, "y"))
+	puts = m.Puts()
+	assert.Len(t, puts, 2)
+	assert.Equal(t, "unpark", puts[0].API)
+	assert.Equal(t, "tracking", puts[1].API) // auto-track
+
+	m.Set("canpark", false)
+	assert.Equal(t, "#", nexstarReply(n, scope, "x"))
+	assert.Empty(t, m.Puts())
+}
+
+func TestNexstarHome(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"canfindhome": true,
+		"athome":      false,
+	})
+	n := NewNexStar(true)
+
+	cmd := []byte{'P', 1, 16, 25, 0, 0, 0, 0}
+	assert.Equal(t, []byte{'#'}, n.nexstarCommand(scope, len(cmd), cmd))
+	puts := m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "findhome", puts[0].API)
+
+	cmd = []byte{'P', 1, 16, 24, 0, 0, 0, 1}
+	assert.Equal(t, []byte{0, '#'}, n.nexstarCommand(scope, len(cmd), cmd))
+	m.Set("athome", true)
+	assert.Equal(t, []byte{0xff, '#'}, n.nexstarCommand(scope, len(cmd), cmd))
+}
+
+func TestNexstarCordWrap(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{})
+	n := NewNexStar(true)
+
+	poll := []byte{'P', 1, 16, 59, 0, 0, 0, 1}
+	assert.Equal(t, []byte{0, '#'}, n.nexstarCommand(scope, len(poll), poll))
+
+	// we can't enforce a cord wrap, so it can't be enabled
+	cmd := []byte{'P', 1, 16, 56, 0, 0, 0, 0}
+	assert.Equal(t, []byte{'#'}, n.nexstarCommand(scope, len(cmd), cmd))
+	assert.Equal(t, []byte{0, '#'}, n.nexstarCommand(scope, len(poll), poll))
+	cmd = []byte{'P', 1, 16, 57, 0, 0, 0, 0}
+	assert.Equal(t, []byte{'#'}, n.nexstarCommand(scope, len(cmd), cmd))
+	assert.Equal(t, []byte{0, '#'}, n.nexstarCommand(scope, len(poll), poll))
+
+	// positions are a 24bit fraction of a rotation
+	cmd = []byte{'P', 4, 16, 58, 0x40, 0x00, 0x00, 0}
+	assert.Equal(t, []byte{'#'}, n.nexstarCommand(scope, len(cmd), cmd))
+	cmd = []byte{'P', 1, 16, 60, 0, 0, 0, 3}
+	assert.Equal(t, []byte{0x40, 0x00, 0x00, '#'}, n.nexstarCommand(scope, len(cmd), cmd))
+
+	// the mount never sees any of this
+	assert.Empty(t, m.Puts())
+}
+
+func TestGPSToDegrees(t *testing.T) {
+	assert.Equal(t, 90.0, GPSToDegrees([]byte{0x40, 0x00, 0x00}))
+	assert.Equal(t, 180.0, GPSToDegrees([]byte{0x80, 0x00, 0x00}))
+	assert.InDelta(t, 40.0, GPSToDegrees(LatLongToGPS(40.0)), 1e-4)
+	assert.InDelta(t, 285.0, GPSToDegrees(LatLongToGPS(-75.0)), 1e-4)
+}
+
+func TestNexstarProfiles(t *testing.T) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"canpark": true,
+	})
+	n := NewNexStar(true)
+	assert.Equal(t, DEFAULT_NEXSTAR_PROFILE, n.Profile.Name)
+
+	tests := []struct {
+		Profile string
+		Version []byte
+		Variant []byte
+		Model   []byte
+	}{
+		{"nexstar-plus", []byte{5, 34, '#'}, []byte{0x11, '#'}, []byte{12, '#'}},
+		{"starsense", []byte{1, 19, '#'}, []byte{0x13, '#'}, []byte{20, '#'}},
+		{"cgx", []byte{5, 34, '#'}, []byte{0x11, '#'}, []byte{23, '#'}},
+		{"evolution", []byte{5, 34, '#'}, []byte{0x11, '#'}, []byte{22, '#'}},
+		{"synscan", []byte("042705#"), []byte{'#'}, []byte{0, '#'}},
+	}
+	for _, test := range tests {
+		profile, err := GetNexStarProfile(test.Profile)
+		assert.NoError(t, err)
+		n.Profile = profile
+		assert.Equal(t, test.Version, []byte(nexstarReply(n, scope, "V")), test.Profile)
+		assert.Equal(t, test.Variant, []byte(nexstarReply(n, scope, "v")), test.Profile)
+		assert.Equal(t, test.Model, []byte(nexstarReply(n, scope, "m")), test.Profile)
+
+		cmd := []byte{'P', 1, 16, 254, 0, 0, 0, 2}
+		assert.Equal(t, []byte{5, 0, '#'}, n.nexstarCommand(scope, len(cmd), cmd), test.Profile)
+	}
+
+	// SynScan has no GPS, RTC or hibernate
+	assert.Equal(t, "#", nexstarReply(n, scope, "x"))
+	assert.Empty(t, m.Puts())
+	cmd := []byte{'P', 1, 176, 254, 0, 0, 0, 2}
+	assert.Equal(t, []byte{'#'}, n.nexstarCommand(scope, len(cmd), cmd))
+	n.Profile, _ = GetNexStarProfile("cgx")
+	assert.Equal(t, []byte{1, 6, '#'}, n.nexstarCommand(scope, len(cmd), cmd))
+
+	_, err := GetNexStarProfile("nexstar")
+	assert.Error(t, err)
+	assert.Equal(t, []string{"cgx", "evolution", "nexstar-plus", "starsense", "synscan"}, NexStarProfileNames())
+}
@@ -2,6 +2,7 @@ package telescope
 
 import (
 	"net"
+	"net/http"
 
 	alpaca "github.com/synfinatic/alpacascope/alpaca"
 )
@@ -9,3 +10,10 @@ import (
 type TelescopeProtocol interface {
 	HandleConnection(net.Conn, *alpaca.Telescope)
 }
+
+// Protocols which run over HTTP are served via an http.Server
+// so that multiple clients can be connected at once
+type HTTPProtocol interface {
+	TelescopeProtocol
+	Handler(*alpaca.Telescope) http.Handler
+}
@@ -0,0 +1,236 @@
+package telescope
+
+/*
+ * Registry of all the TelescopeProtocols we support.  Each protocol
+ * registers its name, factory, options and default port from an init() in
+ * its own file so that the CLI, GUI and config files don't need to know
+ * about every protocol.
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/synfinatic/alpacascope/alpaca"
+)
+
+const (
+	DEFAULT_PROTOCOL = "nexstar"
+)
+
+type OptionType int
+
+const (
+	OptionBool OptionType = iota
+	OptionString
+	OptionInt // non-negative or empty for unset
+)
+
+// A protocol specific option
+type ProtocolOption struct {
+	Name    string                   // CLI/config name: high-precision
+	Label   string                   // GUI label
+	Help    string                   // CLI help
+	Type    OptionType               // OptionBool or OptionString
+	Default string                   // "true"/"false" for OptionBool
+	Choices []string                 // valid values for OptionString, empty for any
+	Check   func(value string) error // additional validation, may be nil
+}
+
+// Everything needed to create a TelescopeProtocol
+type ProtocolConfig struct {
+	AutoTrack bool
+	Options   map[string]string // by ProtocolOption.Name
+}
+
+func NewProtocolConfig(autoTrack bool) ProtocolConfig {
+	return ProtocolConfig{
+		AutoTrack: autoTrack,
+		Options:   map[string]string{},
+	}
+}
+
+func (c ProtocolConfig) String(name string) string {
+	return c.Options[name]
+}
+
+func (c ProtocolConfig) Bool(name string) bool {
+	return c.Options[name] == "true"
+}
+
+type ProtocolFactory func(scope *alpaca.Telescope, config ProtocolConfig) (TelescopeProtocol, error)
+
+type Protocol struct {
+	Name        string // CLI/config name: lx200
+	Label       string // GUI name: LX200
+	Description string
+	DefaultPort int32
+	Order       int // sort order for help & GUI
+	Options     []ProtocolOption
+	Factory     ProtocolFactory
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = map[string]Protocol{}
+)
+
+// Add a protocol to the registry.  Panics if the name is already used.
+func RegisterProtocol(p Protocol) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	name := strings.ToLower(p.Name)
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("protocol %s is already registered", p.Name))
+	}
+	if p.Factory == nil {
+		panic(fmt.Sprintf("protocol %s has no factory", p.Name))
+	}
+	registry[name] = p
+}
+
+// Returns the protocol by Name or Label (case insensitive)
+func GetProtocol(name string) (Protocol, error) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	if p, ok := registry[strings.ToLower(name)]; ok {
+		return p, nil
+	}
+	for _, p := range registry {
+		if strings.EqualFold(p.Label, name) {
+			return p, nil
+		}
+	}
+	return Protocol{}, fmt.Errorf("unknown protocol: %s", name)
+}
+
+// Returns all the registered protocols in order
+func Protocols() []Protocol {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	protocols := []Protocol{}
+	for _, p := range registry {
+		protocols = append(protocols, p)
+	}
+	sort.Slice(protocols, func(i, j int) bool {
+		if protocols[i].Order == protocols[j].Order {
+			return protocols[i].Name < protocols[j].Name
+		}
+		return protocols[i].Order < protocols[j].Order
+	})
+	return protocols
+}
+
+// Returns the Name of every registered protocol
+func ProtocolNames() []string {
+	names := []string{}
+	for _, p := range Protocols() {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// Returns the Label of every registered protocol
+func ProtocolLabels() []string {
+	labels := []string{}
+	for _, p := range Protocols() {
+		labels = append(labels, p.Label)
+	}
+	return labels
+}
+
+// Returns every option supported by any protocol, without duplicates
+func ProtocolOptions() []ProtocolOption {
+	seen := map[string]bool{}
+	options := []ProtocolOption{}
+	for _, p := range Protocols() {
+		for _, o := range p.Options {
+			if !seen[o.Name] {
+				seen[o.Name] = true
+				options = append(options, o)
+			}
+		}
+	}
+	return options
+}
+
+func (p Protocol) Option(name string) (ProtocolOption, bool) {
+	for _, o := range p.Options {
+		if o.Name == name {
+			return o, true
+		}
+	}
+	return ProtocolOption{}, false
+}
+
+func (p Protocol) HasOption(name string) bool {
+	_, ok := p.Option(name)
+	return ok
+}
+
+// Validates the value for an option
+func (o ProtocolOption) Validate(value string) error {
+	switch o.Type {
+	case OptionBool:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("invalid %s: %s", o.Name, value)
+		}
+	case OptionString:
+		if len(o.Choices) == 0 {
+			break
+		}
+		for _, c := range o.Choices {
+			if value == c {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid %s: %s", o.Name, value)
+	case OptionInt:
+		if value == "" {
+			return nil
+		}
+		if _, err := strconv.ParseUint(value, 10, 32); err != nil {
+			return fmt.Errorf("invalid %s: %s", o.Name, value)
+		}
+	}
+	if o.Check != nil {
+		return o.Check(value)
+	}
+	return nil
+}
+
+/*
+ * Creates the TelescopeProtocol.  Options not set in the config use their
+ * defaults and options the protocol doesn't support are ignored.
+ */
+func (p Protocol) New(scope *alpaca.Telescope, config ProtocolConfig) (TelescopeProtocol, error) {
+	c := NewProtocolConfig(config.AutoTrack)
+	for _, o := range p.Options {
+		value, ok := config.Options[o.Name]
+		if !ok {
+			value = o.Default
+		}
+		if err := o.Validate(value); err != nil {
+			return nil, err
+		}
+		c.Options[o.Name] = value
+	}
+	return p.Factory(scope, c)
+}
+
+// Returns the Alpaca tracking mode for the mount-type option
+func MountTypeTrackingMode(mountType string) alpaca.TrackingMode {
+	switch mountType {
+	case "eqn":
+		return alpaca.EQNorth
+	case "eqs":
+		return alpaca.EQSouth
+	}
+	return alpaca.AltAz
+}
@@ -0,0 +1,105 @@
+package telescope
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtocolRegistry(t *testing.T) {
+	assert.Equal(t, []string{"nexstar", "lx200", "stellarium", "websocket", "auto"}, ProtocolNames())
+	assert.Equal(t, []string{"NexStar", "LX200", "Stellarium", "WebSocket", "Auto"}, ProtocolLabels())
+
+	p, err := GetProtocol(DEFAULT_PROTOCOL)
+	assert.NoError(t, err)
+	assert.Equal(t, "NexStar", p.Label)
+
+	// GUI uses the label
+	p, err = GetProtocol("LX200")
+	assert.NoError(t, err)
+	assert.Equal(t, "lx200", p.Name)
+	assert.True(t, p.HasOption("high-precision"))
+	assert.False(t, p.HasOption("mount-type"))
+
+	_, err = GetProtocol("indi")
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		RegisterProtocol(Protocol{Name: "LX200", Factory: p.Factory})
+	})
+
+	names := []string{}
+	for _, o := range ProtocolOptions() {
+		names = append(names, o.Name)
+	}
+	assert.Equal(t, []string{"mount-type", "nexstar-profile", "slew-rates", "high-precision", "lx200-profile", "focuser-id", "settle-time", "passthrough", "sites-file", "allowed-origins", "auto-fallback"}, names)
+}
+
+func TestProtocolOptionValidate(t *testing.T) {
+	assert.NoError(t, highPrecisionOption.Validate("true"))
+	assert.NoError(t, highPrecisionOption.Validate("false"))
+	assert.Error(t, highPrecisionOption.Validate("yes"))
+	assert.NoError(t, mountTypeOption.Validate("eqn"))
+	assert.Error(t, mountTypeOption.Validate("eq"))
+	assert.NoError(t, focuserIDOption.Validate(""))
+	assert.NoError(t, focuserIDOption.Validate("1"))
+	assert.Error(t, focuserIDOption.Validate("-1"))
+	assert.NoError(t, passthroughOption.Validate(""))
+	assert.NoError(t, passthroughOption.Validate(":GX :$QZ=blind"))
+	assert.Error(t, passthroughOption.Validate("GX"))
+	assert.NoError(t, slewRatesOption.Validate(NEXSTAR_SLEW_RATES))
+	assert.Error(t, slewRatesOption.Validate("0.5 1 2"))
+	assert.Error(t, slewRatesOption.Validate("0 1 2 3 4 5 6 7 8"))
+}
+
+func TestProtocolNew(t *testing.T) {
+	scope, _ := newMockTelescope(t, map[string]interface{}{
+		"axisrates": []map[string]float64{{"Maximum": 4.0, "Minimum": 0.0}},
+	})
+
+	p, _ := GetProtocol("lx200")
+	proto, err := p.New(scope, NewProtocolConfig(true))
+	assert.NoError(t, err)
+	lx200 := proto.(*LX200)
+	assert.True(t, lx200.AutoTrack)
+	assert.False(t, lx200.HighPrecision)
+	assert.Equal(t, 4.0, lx200.MaxSlew)
+
+	config := NewProtocolConfig(false)
+	config.Options["high-precision"] = "true"
+	proto, err = p.New(scope, config)
+	assert.NoError(t, err)
+	assert.True(t, proto.(*LX200).HighPrecision)
+
+	config.Options["lx200-profile"] = "classic"
+	proto, err = p.New(scope, config)
+	assert.NoError(t, err)
+	assert.Equal(t, "classic", proto.(*LX200).Profile.Name)
+	assert.Nil(t, proto.(*LX200).Focuser)
+
+	config.Options["focuser-id"] = "2"
+	proto, err = p.New(scope, config)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), proto.(*LX200).Focuser.Id)
+	assert.Equal(t, time.Duration(0), proto.(*LX200).SettleTime)
+
+	config.Options["settle-time"] = "3"
+	proto, err = p.New(scope, config)
+	assert.NoError(t, err)
+	assert.Equal(t, 3*time.Second, proto.(*LX200).SettleTime)
+
+	config.Options["high-precision"] = "maybe"
+	_, err = p.New(scope, config)
+	assert.Error(t, err)
+
+	p, _ = GetProtocol("auto")
+	proto, err = p.New(scope, NewProtocolConfig(true))
+	assert.NoError(t, err)
+	assert.IsType(t, &AutoDetect{}, proto)
+
+	p, _ = GetProtocol("websocket")
+	proto, err = p.New(scope, NewProtocolConfig(true))
+	assert.NoError(t, err)
+	assert.Implements(t, (*HTTPProtocol)(nil), proto)
+}
@@ -0,0 +1,245 @@
+package telescope
+
+/*
+ * Sexagesimal (base 60) formatting and parsing shared by all of our
+ * protocols: RA in hours and Dec/Alt/Az/Lat/Long in degrees.
+ *
+ * Formatting always rounds to the precision being displayed and carries
+ * into the minutes/hours so we never print a seconds value of 60.
+ *
+ * Parsing accepts every LX200 variant (sDD*MM, sDD*MM:SS, sDD*MM'SS,
+ * HH:MM.T, HH:MM:SS, HH:MM:SS.S) as well as human input such as
+ * 12h30m05s, -05°23' or plain decimal numbers.
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type SexagesimalFormat struct {
+	Digits         int     // minimum digits for the whole part: 2 or 3
+	Signed         bool    // always include the +/- sign
+	Wrap           float64 // if > 0, values are normalized to 0 -> Wrap
+	UnitSep        string  // between the whole part and the minutes
+	SecondSep      string  // between the minutes & seconds
+	Seconds        bool    // include seconds
+	TenthsOfMinute bool    // MM.T instead of seconds
+	SecondDecimals int     // digits after the decimal point for seconds
+}
+
+var (
+	FormatHHMMT          = SexagesimalFormat{Digits: 2, Wrap: 24, UnitSep: ":", TenthsOfMinute: true}                                 // HH:MM.T
+	FormatHHMMSS         = SexagesimalFormat{Digits: 2, Wrap: 24, UnitSep: ":", SecondSep: ":", Seconds: true}                        // HH:MM:SS
+	FormatHHMMSSS        = SexagesimalFormat{Digits: 2, Wrap: 24, UnitSep: ":", SecondSep: ":", Seconds: true, SecondDecimals: 1}     // HH:MM:SS.S
+	FormatDDMM           = SexagesimalFormat{Digits: 2, Signed: true, UnitSep: "*"}                                                   // sDD*MM
+	FormatDDMMSS         = SexagesimalFormat{Digits: 2, Signed: true, UnitSep: "*", SecondSep: "'", Seconds: true}                    // sDD*MM'SS
+	FormatDDMMSSColon    = SexagesimalFormat{Digits: 2, Signed: true, UnitSep: "*", SecondSep: ":", Seconds: true}                    // sDD*MM:SS
+	FormatDDMMSSS        = SexagesimalFormat{Digits: 2, Signed: true, UnitSep: "*", SecondSep: ":", Seconds: true, SecondDecimals: 1} // sDD*MM:SS.S
+	FormatDDDMM          = SexagesimalFormat{Digits: 3, Wrap: 360, UnitSep: "*"}                                                      // DDD*MM
+	FormatDDDMMSS        = SexagesimalFormat{Digits: 3, Wrap: 360, UnitSep: "*", SecondSep: "'", Seconds: true}                       // DDD*MM'SS
+	FormatDDDMMSSS       = SexagesimalFormat{Digits: 3, Wrap: 360, UnitSep: "*", SecondSep: ":", Seconds: true, SecondDecimals: 1}    // DDD*MM:SS.S
+	FormatLongitudeDDDMM = SexagesimalFormat{Digits: 3, Signed: true, UnitSep: "*"}                                                   // sDDD*MM
+)
+
+// single byte separators. \xdf is the degree sign on an Autostar
+const sexagesimalSeparators = ":*'\"hmsdHMSD \xdf"
+
+var sexagesimalRunes = []string{"°", "′", "″"}
+
+// Rounds the absolute value to the format's precision and splits it into
+// whole units, minutes and seconds (or tenths of a minute)
+func (f SexagesimalFormat) split(value float64) (bool, int, int, int) {
+	if f.Wrap > 0 {
+		value = math.Mod(value, f.Wrap)
+		if value < 0.0 {
+			value += f.Wrap
+		}
+	}
+
+	steps := 60 // minutes
+	if f.TenthsOfMinute {
+		steps = 600
+	} else if f.Seconds {
+		steps = 3600 * int(math.Pow10(f.SecondDecimals))
+	}
+
+	total := int(math.Round(math.Abs(value) * float64(steps)))
+	if f.Wrap > 0 {
+		total %= int(f.Wrap) * steps
+	}
+	negative := value < 0.0 && total > 0 // no negative zero
+
+	whole := total / steps
+	remain := total % steps
+	minutes := remain / (steps / 60)
+	fraction := remain % (steps / 60) // seconds or tenths of a minute
+	return negative, whole, minutes, fraction
+}
+
+/*
+ * Splits hours or degrees into whole units, minutes and seconds without
+ * rounding, for the HMS and DMS structs.  Only the whole units are negative.
+ */
+func splitSexagesimal(value float64) (int, int, float64) {
+	abs := math.Abs(value)
+	whole := math.Floor(abs)
+	minutes := math.Floor((abs - whole) * 60.0)
+	seconds := (abs - whole - minutes/60.0) * 3600.0
+	if value < 0.0 {
+		whole *= -1.0
+	}
+	return int(whole), int(minutes), seconds
+}
+
+// Inverse of splitSexagesimal: the sign of whole applies to the minutes & seconds
+func joinSexagesimal(whole, minutes int, seconds float64) float64 {
+	value := math.Abs(float64(whole)) + float64(minutes)/60.0 + seconds/3600.0
+	if whole < 0 {
+		value *= -1.0
+	}
+	return value
+}
+
+// Formats hours or degrees
+func (f SexagesimalFormat) Format(value float64) string {
+	negative, whole, minutes, fraction := f.split(value)
+
+	var b strings.Builder
+	if negative {
+		b.WriteByte('-')
+	} else if f.Signed {
+		b.WriteByte('+')
+	}
+	fmt.Fprintf(&b, "%0*d%s%02d", f.Digits, whole, f.UnitSep, minutes)
+
+	switch {
+	case f.TenthsOfMinute:
+		fmt.Fprintf(&b, ".%d", fraction)
+	case f.Seconds && f.SecondDecimals > 0:
+		scale := int(math.Pow10(f.SecondDecimals))
+		fmt.Fprintf(&b, "%s%02d.%0*d", f.SecondSep, fraction/scale, f.SecondDecimals, fraction%scale)
+	case f.Seconds:
+		fmt.Fprintf(&b, "%s%02d", f.SecondSep, fraction)
+	}
+	return b.String()
+}
+
+/*
+ * Parses a sexagesimal or decimal string into hours or degrees.  A
+ * trailing '#' is ignored.  Only the last field may have a decimal
+ * point and minutes/seconds must be less than 60.
+ */
+func ParseSexagesimal(value string) (float64, error) {
+	s := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "#"))
+	if s == "" {
+		return 0.0, fmt.Errorf("empty value")
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	fields := []string{}
+	field := ""
+	for i := 0; i < len(s); {
+		c := s[i]
+		if (c >= '0' && c <= '9') || c == '.' {
+			field += string(c)
+			i++
+			continue
+		}
+
+		n := separatorLen(s[i:])
+		if n == 0 {
+			return 0.0, fmt.Errorf("invalid character in '%s'", value)
+		}
+		if field != "" {
+			fields = append(fields, field)
+			field = ""
+		}
+		i += n
+	}
+	if field != "" {
+		fields = append(fields, field)
+	}
+
+	if len(fields) == 0 || len(fields) > 3 {
+		return 0.0, fmt.Errorf("unable to parse '%s'", value)
+	}
+
+	ret := 0.0
+	for i, f := range fields {
+		if strings.Contains(f, ".") && i != len(fields)-1 {
+			return 0.0, fmt.Errorf("only the last field of '%s' may have a decimal", value)
+		}
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return 0.0, fmt.Errorf("unable to parse '%s': %s", value, err.Error())
+		}
+		if i > 0 && v >= 60.0 {
+			return 0.0, fmt.Errorf("invalid minutes/seconds in '%s'", value)
+		}
+		ret += v / math.Pow(60.0, float64(i))
+	}
+
+	if negative {
+		ret *= -1.0
+	}
+	return ret, nil
+}
+
+// Parses a value and makes sure it is between min & max (inclusive)
+func ParseSexagesimalRange(value string, min, max float64) (float64, error) {
+	v, err := ParseSexagesimal(value)
+	if err != nil {
+		return v, err
+	}
+	if v < min || v > max {
+		return v, fmt.Errorf("'%s' is out of range %g to %g", value, min, max)
+	}
+	return v, nil
+}
+
+// Returns the length of the separator at the start of s or 0 if there isn't one
+func separatorLen(s string) int {
+	for _, r := range sexagesimalRunes {
+		if strings.HasPrefix(s, r) {
+			return len(r)
+		}
+	}
+	if strings.IndexByte(sexagesimalSeparators, s[0]) >= 0 {
+		return 1
+	}
+	return 0
+}
+
+// A value which can be given in JSON as a number or a sexagesimal string
+type Sexagesimal float64
+
+func (v *Sexagesimal) UnmarshalJSON(data []byte) error {
+	var f float64
+	if err := json.Unmarshal(data, &f); err == nil {
+		*v = Sexagesimal(f)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("expected a number or string: %s", string(data))
+	}
+	f, err := ParseSexagesimal(s)
+	if err != nil {
+		return err
+	}
+	*v = Sexagesimal(f)
+	return nil
+}
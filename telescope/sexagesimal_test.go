@@ -0,0 +1,152 @@
+package telescope
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSexagesimalFormat(t *testing.T) {
+	type formatTest struct {
+		Format SexagesimalFormat
+		Value  float64
+		Result string
+	}
+	tests := []formatTest{
+		{FormatHHMMT, 5.0 + 35.0/60 + 17.0/3600, "05:35.3"},
+		{FormatHHMMT, 23.0 + 59.97/60, "00:00.0"},
+		{FormatHHMMSS, 5.0 + 35.0/60 + 17.0/3600, "05:35:17"},
+		{FormatHHMMSS, 10.0 + 59.0/60 + 59.5/3600, "11:00:00"},
+		{FormatHHMMSS, -1.5, "22:30:00"},
+		{FormatHHMMSSS, 5.0 + 35.0/60 + 17.26/3600, "05:35:17.3"},
+		{FormatHHMMSSS, 5.0 + 59.0/60 + 59.96/3600, "06:00:00.0"},
+		{FormatDDMM, -5.5, "-05*30"},
+		{FormatDDMM, -0.5, "-00*30"},
+		{FormatDDMM, -0.001, "+00*00"},
+		{FormatDDMM, 45.0 + 59.6/60, "+46*00"},
+		{FormatDDMMSS, -5.0 - 23.0/60 - 28.0/3600, "-05*23'28"},
+		{FormatDDMMSS, 89.0 + 59.0/60 + 59.7/3600, "+90*00'00"},
+		{FormatDDMMSSColon, -5.0 - 23.0/60 - 28.0/3600, "-05*23:28"},
+		{FormatDDMMSSS, -5.0 - 23.0/60 - 28.04/3600, "-05*23:28.0"},
+		{FormatDDMMSSS, 12.0 + 34.0/60 + 56.78/3600, "+12*34:56.8"},
+		{FormatDDDMM, 359.999, "000*00"},
+		{FormatDDDMMSS, 271.0 + 12.0/60 + 17.49/3600, "271*12'17"},
+		{FormatDDDMMSS, -90.0, "270*00'00"},
+		{FormatDDDMMSSS, 271.0 + 12.0/60 + 17.49/3600, "271*12:17.5"},
+		{FormatLongitudeDDDMM, -122.5, "-122*30"},
+		{FormatLongitudeDDDMM, 7.25, "+007*15"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.Result, test.Format.Format(test.Value), "%g", test.Value)
+	}
+}
+
+func TestParseSexagesimal(t *testing.T) {
+	tests := map[string]float64{
+		// LX200
+		"+45*30":       45.5,
+		"-05*30":       -5.5,
+		"-00*30":       -0.5,
+		"-05*23:28":    -(5.0 + 23.0/60 + 28.0/3600),
+		"-05*23'28":    -(5.0 + 23.0/60 + 28.0/3600),
+		"+12*34:56.8":  12.0 + 34.0/60 + 56.8/3600,
+		"05:35.3":      5.0 + 35.3/60,
+		"05:35:17":     5.0 + 35.0/60 + 17.0/3600,
+		"05:35:17.3#":  5.0 + 35.0/60 + 17.3/3600,
+		"122*30":       122.5,
+		"+38\xdf47":    38.0 + 47.0/60, // Autostar degree sign
+		"271*12'17":    271.0 + 12.0/60 + 17.0/3600,
+		"-122*30:00.0": -122.5,
+		// human
+		"12h30m05s":   12.0 + 30.0/60 + 5.0/3600,
+		"12h 30m":     12.5,
+		"-05°23'":     -(5.0 + 23.0/60),
+		"-05°23'28\"": -(5.0 + 23.0/60 + 28.0/3600),
+		"+5d23m28s":   5.0 + 23.0/60 + 28.0/3600,
+		"10 30 00":    10.5,
+		"38°47′01″":   38.0 + 47.0/60 + 1.0/3600,
+		// decimal
+		"12.5":   12.5,
+		"-0.25":  -0.25,
+		" 5.59 ": 5.59,
+	}
+	for input, expected := range tests {
+		v, err := ParseSexagesimal(input)
+		assert.NoError(t, err, input)
+		assert.InDelta(t, expected, v, 1e-9, input)
+	}
+
+	errors := []string{
+		"",
+		"#",
+		"abc",
+		"12:60",
+		"12:30:60",
+		"12.5:30",
+		"1:2:3:4",
+		"12x30",
+		"--5*30",
+		"12é30",
+	}
+	for _, input := range errors {
+		_, err := ParseSexagesimal(input)
+		assert.Error(t, err, input)
+	}
+
+	_, err := ParseSexagesimalRange("+91*00", -90.0, 90.0)
+	assert.Error(t, err)
+	v, err := ParseSexagesimalRange("-90*00", -90.0, 90.0)
+	assert.NoError(t, err)
+	assert.Equal(t, -90.0, v)
+}
+
+// formatting and parsing should round trip at the format's precision
+func TestSexagesimalRoundTrip(t *testing.T) {
+	formats := []SexagesimalFormat{FormatHHMMSS, FormatHHMMSSS, FormatDDMMSS, FormatDDMMSSColon, FormatDDMMSSS}
+	for _, f := range formats {
+		for _, value := range []float64{0.0, 1.2345, 5.999999, 12.5, 23.75} {
+			if f.Signed {
+				value = -value
+			}
+			v, err := ParseSexagesimal(f.Format(value))
+			assert.NoError(t, err)
+			assert.InDelta(t, value, v, 0.5/3600.0, f.Format(value))
+		}
+	}
+}
+
+func TestSexagesimalJSON(t *testing.T) {
+	var v struct {
+		RA  Sexagesimal `json:"ra"`
+		Dec Sexagesimal `json:"dec"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(`{"ra": "12h30m", "dec": -5.5}`), &v))
+	assert.Equal(t, Sexagesimal(12.5), v.RA)
+	assert.Equal(t, Sexagesimal(-5.5), v.Dec)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"ra": "12h60m"}`), &v))
+	assert.Error(t, json.Unmarshal([]byte(`{"ra": true}`), &v))
+}
+
+func TestSplitSexagesimal(t *testing.T) {
+	d, m, s := splitSexagesimal(-1.9166666666666665)
+	assert.Equal(t, -1, d)
+	assert.Equal(t, 54, m)
+	assert.InDelta(t, 60.0, s, 1e-9)
+	assert.InDelta(t, -1.9166666666666665, joinSexagesimal(d, m, s), 1e-12)
+
+	d, m, s = splitSexagesimal(12.505)
+	assert.Equal(t, 12, d)
+	assert.Equal(t, 30, m)
+	assert.InDelta(t, 18.0, s, 1e-9)
+	assert.Equal(t, 12.505, joinSexagesimal(12, 30, 18.0))
+
+	// HMS & DMS use the same conversion
+	hms := NewHMSHours(12.505)
+	assert.Equal(t, NewHMS(12, 30, hms.Seconds), hms)
+	dms := NewDMSDegrees(12.505)
+	assert.Equal(t, 12, dms.Degrees)
+	assert.Equal(t, 30, dms.Minutes)
+	assert.InDelta(t, 18.0, dms.Seconds, 1e-9)
+}
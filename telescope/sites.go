@@ -0,0 +1,183 @@
+package telescope
+
+/*
+ * Observing sites for the LX200 :W1# - :W4# commands.  Like a Meade hand
+ * controller we remember four named sites so observers moving between home
+ * and a dark site don't have to re-enter their location.  Sites are saved
+ * as JSON so they survive restarting AlpacaScope.
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/synfinatic/alpacascope/alpaca"
+)
+
+const (
+	MAX_SITES       = 4
+	MAX_SITE_NAME   = 15 // Autostar limit
+	SITES_FILE_NAME = "sites.json"
+)
+
+type Site struct {
+	Name          string  `json:"name"`
+	Latitude      float64 `json:"latitude"`        // degrees, + North
+	Longitude     float64 `json:"longitude"`       // degrees, + East like ASCOM
+	Elevation     float64 `json:"elevation"`       // meters
+	UTCOffset     float64 `json:"utc_offset"`      // hours added to local time to get UTC
+	HaveLocation  bool    `json:"have_location"`   // latitude/longitude/elevation are set
+	HaveUTCOffset bool    `json:"have_utc_offset"` // UTCOffset is set
+}
+
+type SiteStore struct {
+	path    string // empty to not save
+	lock    sync.Mutex
+	Current int             `json:"current"` // 0 to MAX_SITES - 1
+	Sites   [MAX_SITES]Site `json:"sites"`
+}
+
+var (
+	siteStoresLock sync.Mutex
+	siteStores     = map[string]*SiteStore{}
+)
+
+// Returns ~/.alpacascope/sites.json
+func DefaultSitesPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Errorf("Unable to find home directory: %s", err.Error())
+		return SITES_FILE_NAME
+	}
+	return filepath.Join(home, ".alpacascope", SITES_FILE_NAME)
+}
+
+/*
+ * Returns the sites saved in the given file, which is shared by all
+ * listeners using the same file.  An empty path keeps the sites in memory.
+ */
+func NewSiteStore(path string) *SiteStore {
+	if path != "" {
+		siteStoresLock.Lock()
+		defer siteStoresLock.Unlock()
+		if s, ok := siteStores[path]; ok {
+			return s
+		}
+	}
+
+	s := &SiteStore{path: path}
+	for i := range s.Sites {
+		s.Sites[i].Name = fmt.Sprintf("Site %d", i+1)
+	}
+	if path != "" {
+		if err := s.load(); err != nil && !os.IsNotExist(err) {
+			log.Errorf("Unable to load sites from %s: %s", path, err.Error())
+		}
+		siteStores[path] = s
+	}
+	return s
+}
+
+func (s *SiteStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, s); err != nil {
+		return err
+	}
+	if s.Current < 0 || s.Current >= MAX_SITES {
+		s.Current = 0
+	}
+	return nil
+}
+
+// must hold the lock
+func (s *SiteStore) save() {
+	if s.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(s.path), 0755); err == nil {
+			err = os.WriteFile(s.path, data, 0600)
+		}
+	}
+	if err != nil {
+		log.Errorf("Unable to save sites to %s: %s", s.path, err.Error())
+	}
+}
+
+// Returns the site (0 to MAX_SITES - 1)
+func (s *SiteStore) Site(i int) Site {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.Sites[i]
+}
+
+// Returns the index of the current site
+func (s *SiteStore) CurrentSite() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.Current
+}
+
+func (s *SiteStore) SetName(i int, name string) error {
+	if len(name) == 0 || len(name) > MAX_SITE_NAME {
+		return fmt.Errorf("invalid site name: '%s'", name)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Sites[i].Name = name
+	s.save()
+	return nil
+}
+
+// Makes the site current and returns it
+func (s *SiteStore) Select(i int) Site {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Current = i
+	s.save()
+	return s.Sites[i]
+}
+
+// Updates the location of the current site
+func (s *SiteStore) SetLocation(lat, long, elevation float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	site := &s.Sites[s.Current]
+	site.Latitude, site.Longitude, site.Elevation = lat, long, elevation
+	site.HaveLocation = true
+	s.save()
+}
+
+// Updates the UTC offset of the current site
+func (s *SiteStore) SetUTCOffset(offset float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Sites[s.Current].UTCOffset = offset
+	s.Sites[s.Current].HaveUTCOffset = true
+	s.save()
+}
+
+// Sends the site's location to the mount
+func (site Site) Send(t *alpaca.Telescope) error {
+	if !site.HaveLocation {
+		return nil
+	}
+	if err := t.PutSiteLatitude(site.Latitude); err != nil {
+		return fmt.Errorf("unable to set site latitude: %s", err.Error())
+	}
+	if err := t.PutSiteLongitude(site.Longitude); err != nil {
+		return fmt.Errorf("unable to set site longitude: %s", err.Error())
+	}
+	if err := t.PutSiteElevation(site.Elevation); err != nil {
+		return fmt.Errorf("unable to set site elevation: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package telescope
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSiteStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alpacascope", SITES_FILE_NAME)
+	s := NewSiteStore(path)
+	assert.Equal(t, "Site 1", s.Site(0).Name)
+	assert.Equal(t, 0, s.CurrentSite())
+	assert.False(t, s.Site(0).HaveLocation)
+
+	assert.NoError(t, s.SetName(1, "Dark Site"))
+	assert.Error(t, s.SetName(1, ""))
+	assert.Error(t, s.SetName(1, "A Very Long Site Name"))
+	s.Select(1)
+	s.SetLocation(37.5, -121.75, 1200.0)
+	s.SetUTCOffset(8.0)
+
+	// the same file shares the same sites
+	assert.Same(t, s, NewSiteStore(path))
+
+	// and they are reloaded from the file
+	delete(siteStores, path)
+	loaded := NewSiteStore(path)
+	assert.NotSame(t, s, loaded)
+	assert.Equal(t, 1, loaded.CurrentSite())
+	assert.Equal(t, Site{
+		Name:          "Dark Site",
+		Latitude:      37.5,
+		Longitude:     -121.75,
+		Elevation:     1200.0,
+		UTCOffset:     8.0,
+		HaveLocation:  true,
+		HaveUTCOffset: true,
+	}, loaded.Site(1))
+	assert.Equal(t, "Site 3", loaded.Site(2).Name)
+
+	// memory only
+	assert.NotSame(t, NewSiteStore(""), NewSiteStore(""))
+}
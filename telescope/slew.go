@@ -0,0 +1,69 @@
+package telescope
+
+/*
+ * Checks shared by every protocol before slewing to an RA/Dec so that no
+ * client can slew a parked mount or to a target outside the altitude limits.
+ */
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/synfinatic/alpacascope/alpaca"
+)
+
+// Why checkSlew() refused a slew
+type slewRefusal int
+
+const (
+	slewNotSupported slewRefusal = iota + 1
+	slewParked
+	slewBelowLimit
+	slewAboveLimit
+)
+
+type slewError struct {
+	Refusal slewRefusal
+	Message string
+}
+
+func (e *slewError) Error() string {
+	return e.Message
+}
+
+/*
+ * Returns nil if the mount can slew to the RA (hours) & Dec (degrees) and
+ * the target is between the lower and upper altitude limits (degrees).
+ * The limits are only checked if the mount knows its site & time.
+ */
+func checkSlew(t *alpaca.Telescope, ra, dec, lower, upper float64) *slewError {
+	canSlew, err := t.GetCanSlewAsync()
+	if err != nil {
+		log.Warnf("Unable to get canslewasync: %s", err.Error())
+	} else if !canSlew {
+		return &slewError{slewNotSupported, "mount does not support SlewToCoordinatesAsync"}
+	}
+
+	if parked, err := t.GetAtPark(); err == nil && parked {
+		return &slewError{slewParked, "mount is parked"}
+	}
+
+	// only check the limits if we know where the target is in the sky
+	lat, laterr := t.GetSiteLatitude()
+	long, longerr := t.GetSiteLongitude()
+	now, nowerr := t.GetUTCDate()
+	if laterr != nil || longerr != nil || nowerr != nil {
+		log.Warnf("Unable to check target altitude without the site location & time")
+		return nil
+	}
+
+	ha := (LocalSiderealTime(now, long) - ra) * 15.0
+	alt := GetAlt(ha, dec, lat)
+	log.Debugf("ra %g dec %g is alt %g", ra, dec, alt)
+	if alt < lower {
+		return &slewError{slewBelowLimit, fmt.Sprintf("target altitude %g is below the limit %g", alt, lower)}
+	} else if alt > upper {
+		return &slewError{slewAboveLimit, fmt.Sprintf("target altitude %g is above the limit %g", alt, upper)}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package telescope
+
+/*
+ * Stellarium Telescope Control binary protocol
+ * http://svn.code.sf.net/p/stellarium/code/trunk/telescope_server/stellarium_telescope_protocol.txt
+ *
+ * Unlike LX200 and NexStar, the client never asks for the current position.
+ * Instead, the server pushes a "current position" message every so often and
+ * the client only speaks when it wants us to goto a new position.
+ */
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/synfinatic/alpacascope/alpaca"
+)
+
+const (
+	STELLARIUM_GOTO_LEN     = 20 // client -> server
+	STELLARIUM_POSITION_LEN = 24 // server -> client
+	STELLARIUM_UPDATE_RATE  = 500 * time.Millisecond
+)
+
+type Stellarium struct {
+	AutoTrack bool // ensure tracking is enabled for goto
+}
+
+func NewStellarium(autoTrack bool) *Stellarium {
+	return &Stellarium{
+		AutoTrack: autoTrack,
+	}
+}
+
+func init() {
+	RegisterProtocol(Protocol{
+		Name:        "stellarium",
+		Label:       "Stellarium",
+		Description: "Stellarium Telescope Control binary protocol",
+		DefaultPort: 10001,
+		Order:       30,
+		Factory: func(scope *alpaca.Telescope, config ProtocolConfig) (TelescopeProtocol, error) {
+			return NewStellarium(config.AutoTrack), nil
+		},
+	})
+}
+
+func (s *Stellarium) HandleConnection(conn net.Conn, t *alpaca.Telescope) {
+	defer conn.Close() // make sure we close connection before we leave
+
+	// reader goroutine tells us when the client has gone away
+	done := make(chan bool)
+	go s.readGoto(conn, t, done)
+
+	ticker := time.NewTicker(STELLARIUM_UPDATE_RATE)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case <-ticker.C:
+			ra, dec, err := t.GetRaDec()
+			if err != nil {
+				log.Errorf("unable to get RA/DEC: %s", err.Error())
+				continue
+			}
+			_, err = conn.Write(StellariumPosition(ra, dec, time.Now()))
+			if err != nil {
+				log.Errorf("writing position to Stellarium client: %s", err.Error())
+				return
+			}
+		}
+	}
+}
+
+// readGoto processes goto messages until the client disconnects
+func (s *Stellarium) readGoto(conn net.Conn, t *alpaca.Telescope, done chan bool) {
+	defer close(done)
+
+	hdr := make([]byte, 2)
+	for {
+		_, err := io.ReadFull(conn, hdr)
+		if err != nil {
+			if err != io.EOF {
+				log.Errorf("conn.Read() returned error: %s", err.Error())
+			}
+			return
+		}
+
+		msgLen := int(binary.LittleEndian.Uint16(hdr))
+		if msgLen < 4 {
+			log.Errorf("invalid Stellarium message length: %d", msgLen)
+			return
+		}
+
+		msg := make([]byte, msgLen)
+		copy(msg, hdr)
+		_, err = io.ReadFull(conn, msg[2:])
+		if err != nil {
+			log.Errorf("conn.Read() returned error: %s", err.Error())
+			return
+		}
+
+		ra, dec, err := ParseStellariumGoto(msg)
+		if err != nil {
+			log.Errorf("%s", err.Error())
+			continue
+		}
+
+		log.Debugf("Stellarium goto RA: %g, Dec: %g", ra, dec)
+		if s.AutoTrack {
+			// auto-enable tracking?
+			mode, err := t.GetTracking()
+			if err != nil {
+				log.Errorf("unable to get tracking mode: %s", err.Error())
+			} else if mode == alpaca.NotTracking {
+				err = t.PutTracking(alpaca.AltAz) // need any non-NotTracking value for true
+				if err != nil {
+					log.Errorf("unable to auto-enable tracking: %s", err.Error())
+				}
+			}
+		}
+
+		err = t.PutSlewToCoordinatestAsync(ra, dec)
+		if err != nil {
+			log.Errorf("error talking to scope: %s", err.Error())
+		}
+	}
+}
+
+/*
+ * Goto message:
+ * LENGTH (2 bytes, 20), TYPE (2 bytes, 0), TIME (8 bytes),
+ * RA (4 bytes, unsigned), DEC (4 bytes, signed)
+ *
+ * Returns RA in hours and Dec in degrees
+ */
+func ParseStellariumGoto(msg []byte) (float64, float64, error) {
+	if len(msg) != STELLARIUM_GOTO_LEN {
+		return 0.0, 0.0, fmt.Errorf("invalid Stellarium goto length: %d", len(msg))
+	}
+
+	msgType := binary.LittleEndian.Uint16(msg[2:4])
+	if msgType != 0 {
+		return 0.0, 0.0, fmt.Errorf("unsupported Stellarium message type: %d", msgType)
+	}
+
+	raInt := binary.LittleEndian.Uint32(msg[12:16])
+	decInt := int32(binary.LittleEndian.Uint32(msg[16:20])) // nolint:gosec
+
+	// 0x100000000 = 24h, 0x40000000 = 90deg
+	ra := float64(raInt) / math.Pow(2, 32) * 24.0
+	dec := float64(decInt) / math.Pow(2, 30) * 90.0
+	return ra, dec, nil
+}
+
+/*
+ * Current position message:
+ * LENGTH (2 bytes, 24), TYPE (2 bytes, 0), TIME (8 bytes),
+ * RA (4 bytes, unsigned), DEC (4 bytes, signed), STATUS (4 bytes, 0 = OK)
+ */
+func StellariumPosition(ra float64, dec float64, now time.Time) []byte {
+	msg := make([]byte, STELLARIUM_POSITION_LEN)
+	binary.LittleEndian.PutUint16(msg[0:2], STELLARIUM_POSITION_LEN)
+	binary.LittleEndian.PutUint16(msg[2:4], 0)
+	binary.LittleEndian.PutUint64(msg[4:12], uint64(now.UnixMicro())) // nolint:gosec
+	binary.LittleEndian.PutUint32(msg[12:16], uint32(math.Mod(ra, 24.0)/24.0*math.Pow(2, 32)))
+	binary.LittleEndian.PutUint32(msg[16:20], uint32(int32(dec/90.0*math.Pow(2, 30)))) // nolint:gosec
+	binary.LittleEndian.PutUint32(msg[20:24], 0)
+	return msg
+}
@@ -0,0 +1,48 @@
+package telescope
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStellariumGoto(t *testing.T) {
+	type gotoTest struct {
+		RA  uint32
+		Dec int32
+	}
+	tests := map[Coordinates]gotoTest{
+		{RA: 0.0, Dec: 0.0}:    {RA: 0, Dec: 0},
+		{RA: 12.0, Dec: 90.0}:  {RA: 0x80000000, Dec: 0x40000000},
+		{RA: 6.0, Dec: -90.0}:  {RA: 0x40000000, Dec: -0x40000000},
+		{RA: 18.0, Dec: 45.0}:  {RA: 0xc0000000, Dec: 0x20000000},
+		{RA: 3.0, Dec: -22.5}:  {RA: 0x20000000, Dec: -0x10000000},
+		{RA: 21.0, Dec: 11.25}: {RA: 0xe0000000, Dec: 0x08000000},
+	}
+
+	for check, test := range tests {
+		msg := make([]byte, STELLARIUM_GOTO_LEN)
+		binary.LittleEndian.PutUint16(msg[0:2], STELLARIUM_GOTO_LEN)
+		binary.LittleEndian.PutUint32(msg[12:16], test.RA)
+		binary.LittleEndian.PutUint32(msg[16:20], uint32(test.Dec))
+		ra, dec, err := ParseStellariumGoto(msg)
+		assert.NoError(t, err)
+		assert.Equal(t, check.RA, ra)
+		assert.Equal(t, check.Dec, dec)
+
+		// and back again
+		pos := StellariumPosition(ra, dec, time.Unix(0, 0))
+		assert.Equal(t, STELLARIUM_POSITION_LEN, len(pos))
+		assert.Equal(t, msg[12:20], pos[12:20])
+	}
+
+	_, _, err := ParseStellariumGoto([]byte{4, 0, 0, 0})
+	assert.Error(t, err)
+
+	msg := make([]byte, STELLARIUM_GOTO_LEN)
+	binary.LittleEndian.PutUint16(msg[2:4], 1)
+	_, _, err = ParseStellariumGoto(msg)
+	assert.Error(t, err)
+}
@@ -1,7 +1,7 @@
 package telescope
 
 /*
- * Note that all of these functions are NOT used by AlpacaScope!
+ * Sidereal time calculations used by LX200 :GS# and alt/az conversions.
  * Formulas from https://thecynster.home.blog/2019/11/04/calculating-sidereal-time/
  */
 import (
@@ -9,6 +9,10 @@ import (
 	"time"
 )
 
+const (
+	SIDEREAL_RATE = 15.041067 / 3600.0 // degrees/sec
+)
+
 // Returns days.frac_day since J2000 for UTC time
 func TimeToJ2000(t time.Time) float64 {
 	return TimeToJYear(2000, t)
@@ -53,7 +57,16 @@ func TimeToJYear(jyear int, t time.Time) float64 {
 // Convert Greenwich Mean Siderial Time to Local Siderial Time (hours)
 func GMSTToLST(gmst float64, longitudeHrs float64) float64 {
 	lst := gmst + longitudeHrs // must be in hrs, not degrees!
-	return math.Mod(lst, 24.0)
+	lst = math.Mod(lst, 24.0)
+	if lst < 0.0 {
+		lst += 24.0
+	}
+	return lst
+}
+
+// Returns the Local Sidereal Time (hours) for the longitude (degrees, + East)
+func LocalSiderealTime(t time.Time, longitude float64) float64 {
+	return GMSTToLST(GreenwichMeanSiderealTime(t), longitude/15.0)
 }
 
 // Returns hour.hour_frac
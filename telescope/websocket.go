@@ -0,0 +1,479 @@
+package telescope
+
+/*
+ * WebSocket + JSON protocol for browser based planetariums and dashboards.
+ *
+ * Clients connect to ws://host:port/ws and send requests like:
+ *
+ * {"id": 1, "command": "goto", "ra": 5.59, "dec": -5.39}
+ *
+ * and get back a reply with the same id:
+ *
+ * {"type": "reply", "id": 1, "ok": true}
+ *
+ * Commands:
+ * goto, sync      - ra (hours) & dec (degrees) as numbers or strings
+ *                   such as "05h35m24s" and "-05°23'"
+ * move            - axis ("ra" or "dec") & rate (deg/sec, 0 to stop)
+ * stop            - abort any slew or move
+ * tracking        - enabled (true/false)
+ * position        - reply includes the current position
+ * subscribe       - stream position messages every interval (msec)
+ * unsubscribe     - stop the position stream
+ *
+ * Browsers send an Origin header, so only pages served from the same host
+ * as the WebSocket or listed in AllowedOrigins may connect.  Clients which
+ * don't send an Origin (non-browser software) are always allowed.
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/synfinatic/alpacascope/alpaca"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	WEBSOCKET_PATH             = "/ws"
+	WEBSOCKET_DEFAULT_INTERVAL = 1000 // msec
+	WEBSOCKET_MIN_INTERVAL     = 100  // msec
+)
+
+type WebSocketRequest struct {
+	ID       int          `json:"id"`
+	Command  string       `json:"command"`
+	RA       *Sexagesimal `json:"ra,omitempty"`  // hours: 5.59 or "05h35m24s"
+	Dec      *Sexagesimal `json:"dec,omitempty"` // degrees: -5.39 or "-05°23'"
+	Axis     string       `json:"axis,omitempty"`
+	Rate     float64      `json:"rate,omitempty"`
+	Enabled  *bool        `json:"enabled,omitempty"`
+	Interval int          `json:"interval,omitempty"`
+}
+
+type WebSocketReply struct {
+	Type     string             `json:"type"` // reply, position
+	ID       int                `json:"id,omitempty"`
+	OK       bool               `json:"ok"`
+	Error    string             `json:"error,omitempty"`
+	Position *WebSocketPosition `json:"position,omitempty"`
+}
+
+type WebSocketPosition struct {
+	RA       float64   `json:"ra"`  // hours
+	Dec      float64   `json:"dec"` // degrees
+	Alt      float64   `json:"alt"`
+	Az       float64   `json:"az"`
+	Slewing  bool      `json:"slewing"`
+	Tracking bool      `json:"tracking"`
+	Time     time.Time `json:"time"`
+}
+
+type WebSocket struct {
+	AutoTrack      bool     // ensure tracking is enabled for goto
+	AllowedOrigins []string // extra origins to accept: http://host:port or *
+}
+
+func NewWebSocket(autoTrack bool) *WebSocket {
+	return &WebSocket{
+		AutoTrack: autoTrack,
+	}
+}
+
+var allowedOriginsOption = ProtocolOption{
+	Name:  "allowed-origins",
+	Label: "WebSocket Allowed Origins",
+	Help:  "Space separated web page origins allowed to use the WebSocket: http://host:port or *",
+	Type:  OptionString,
+	Check: func(value string) error {
+		_, err := ParseWebSocketOrigins(value)
+		return err
+	},
+}
+
+func init() {
+	RegisterProtocol(Protocol{
+		Name:        "websocket",
+		Label:       "WebSocket",
+		Description: "WebSocket + JSON for browser clients",
+		DefaultPort: 8030,
+		Order:       40,
+		Options:     []ProtocolOption{allowedOriginsOption},
+		Factory: func(scope *alpaca.Telescope, config ProtocolConfig) (TelescopeProtocol, error) {
+			ws := NewWebSocket(config.AutoTrack)
+			ws.AllowedOrigins, _ = ParseWebSocketOrigins(config.String("allowed-origins")) // already validated
+			return ws, nil
+		},
+	})
+}
+
+// Returns the http.Handler which serves our WebSocket
+func (w *WebSocket) Handler(t *alpaca.Telescope) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(WEBSOCKET_PATH, websocket.Server{
+		Handshake: w.handshake,
+		Handler: func(ws *websocket.Conn) {
+			w.serve(ws, t)
+		},
+	})
+	return mux
+}
+
+// Rejects browsers connecting from web pages on other sites
+func (w *WebSocket) handshake(config *websocket.Config, req *http.Request) error {
+	origin, err := websocket.Origin(config, req)
+	if err != nil {
+		return err
+	}
+	config.Origin = origin
+	if origin == nil || w.allowOrigin(origin, req.Host) {
+		return nil
+	}
+	log.Errorf("Rejecting WebSocket client from %s with Origin %s", req.RemoteAddr, origin.String())
+	return fmt.Errorf("origin %s is not allowed", origin.String())
+}
+
+func (w *WebSocket) allowOrigin(origin *url.URL, host string) bool {
+	if strings.EqualFold(origin.Host, host) {
+		return true
+	}
+	for _, allowed := range w.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin.Scheme+"://"+origin.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the space separated origins for the allowed-origins option
+func ParseWebSocketOrigins(value string) ([]string, error) {
+	origins := []string{}
+	for _, origin := range strings.Fields(value) {
+		if origin != "*" {
+			u, err := url.Parse(origin)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return []string{}, fmt.Errorf("invalid origin: %s", origin)
+			}
+		}
+		origins = append(origins, origin)
+	}
+	return origins, nil
+}
+
+// Serve a single HTTP client.  Normally Listener uses Handler() instead.
+func (w *WebSocket) HandleConnection(conn net.Conn, t *alpaca.Telescope) {
+	ln := newOneConnListener(conn)
+	_ = http.Serve(ln, w.Handler(t)) // nolint:gosec
+}
+
+// per-connection state
+type webSocketClient struct {
+	ws        *websocket.Conn
+	t         *alpaca.Telescope
+	autoTrack bool
+	lock      sync.Mutex
+	subscribe chan int  // new subscription interval, 0 to stop
+	maxRates  []float64 // by alpaca.AxisType
+}
+
+func (w *WebSocket) serve(ws *websocket.Conn, t *alpaca.Telescope) {
+	defer ws.Close()
+	log.Debugf("WebSocket client connected from %s", ws.Request().RemoteAddr)
+
+	c := &webSocketClient{
+		ws:        ws,
+		t:         t,
+		autoTrack: w.AutoTrack,
+		subscribe: make(chan int),
+	}
+	done := make(chan bool)
+	defer close(done)
+	go c.stream(done)
+
+	for {
+		var msg []byte
+		var req WebSocketRequest
+		err := websocket.Message.Receive(ws, &msg)
+		if err != nil {
+			log.Debugf("WebSocket client closed: %s", err.Error())
+			return
+		}
+		if err = json.Unmarshal(msg, &req); err != nil {
+			c.send(WebSocketReply{Type: "reply", Error: fmt.Sprintf("invalid request: %s", err.Error())})
+			continue
+		}
+		c.send(c.command(req))
+	}
+}
+
+func (c *webSocketClient) send(reply WebSocketReply) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := websocket.JSON.Send(c.ws, reply); err != nil {
+		log.Errorf("writing reply to WebSocket client: %s", err.Error())
+	}
+}
+
+func (c *webSocketClient) command(req WebSocketRequest) WebSocketReply {
+	var err error
+	reply := WebSocketReply{
+		Type: "reply",
+		ID:   req.ID,
+	}
+
+	switch req.Command {
+	case "goto", "sync":
+		var ra, dec float64
+		ra, dec, err = validateRaDec(req.RA, req.Dec)
+		if err != nil {
+			break
+		}
+		if req.Command == "sync" {
+			err = c.t.PutSyncToCoordinates(ra, dec)
+			break
+		}
+		// the horizon is our only limit
+		if serr := checkSlew(c.t, ra, dec, 0.0, 90.0); serr != nil {
+			err = serr
+			break
+		}
+		if c.autoTrack {
+			c.enableTracking()
+		}
+		err = c.t.PutSlewToCoordinatestAsync(ra, dec)
+
+	case "move":
+		var axis alpaca.AxisType
+		switch req.Axis {
+		case "ra":
+			axis = alpaca.AxisAzmRa
+		case "dec":
+			axis = alpaca.AxisAltDec
+		default:
+			err = fmt.Errorf("invalid axis: %s", req.Axis)
+		}
+		if err == nil {
+			err = c.checkRate(axis, req.Rate)
+		}
+		if err == nil {
+			err = c.t.PutMoveAxis(axis, req.Rate)
+		}
+
+	case "stop":
+		err = c.t.PutAbortSlew()
+		if e := c.t.PutMoveAxis(alpaca.AxisAzmRa, 0); e != nil && err == nil {
+			err = e
+		}
+		if e := c.t.PutMoveAxis(alpaca.AxisAltDec, 0); e != nil && err == nil {
+			err = e
+		}
+
+	case "tracking":
+		if req.Enabled == nil {
+			err = fmt.Errorf("missing enabled")
+			break
+		}
+		mode := alpaca.NotTracking
+		if *req.Enabled {
+			mode = c.t.Tracking
+		}
+		err = c.t.PutTracking(mode)
+
+	case "position":
+		reply.Position, err = c.position()
+
+	case "subscribe":
+		interval := req.Interval
+		if interval == 0 {
+			interval = WEBSOCKET_DEFAULT_INTERVAL
+		} else if interval < WEBSOCKET_MIN_INTERVAL {
+			interval = WEBSOCKET_MIN_INTERVAL
+		}
+		c.subscribe <- interval
+
+	case "unsubscribe":
+		c.subscribe <- 0
+
+	default:
+		err = fmt.Errorf("unsupported command: %s", req.Command)
+	}
+
+	if err != nil {
+		log.Errorf("WebSocket %s: %s", req.Command, err.Error())
+		reply.Error = err.Error()
+	} else {
+		reply.OK = true
+	}
+	return reply
+}
+
+// Makes sure the RA (hours) and Dec (degrees) are valid coordinates
+func validateRaDec(raValue, decValue *Sexagesimal) (float64, float64, error) {
+	if raValue == nil || decValue == nil {
+		return 0.0, 0.0, fmt.Errorf("missing ra or dec")
+	}
+	ra, dec := float64(*raValue), float64(*decValue)
+	if ra < 0.0 || ra >= 24.0 {
+		return 0.0, 0.0, fmt.Errorf("invalid ra: %g", ra)
+	}
+	if dec < -90.0 || dec > 90.0 {
+		return 0.0, 0.0, fmt.Errorf("invalid dec: %g", dec)
+	}
+	return ra, dec, nil
+}
+
+// Make sure the rate is within what the mount can do
+func (c *webSocketClient) checkRate(axis alpaca.AxisType, rate float64) error {
+	if c.maxRates == nil {
+		c.maxRates = make([]float64, alpaca.AxisTertiary)
+		for _, a := range []alpaca.AxisType{alpaca.AxisAzmRa, alpaca.AxisAltDec} {
+			rates, err := c.t.GetAxisRates(a)
+			if err != nil {
+				c.maxRates = nil
+				return fmt.Errorf("unable to query axis rates: %s", err.Error())
+			}
+			c.maxRates[a] = rates["Maximum"]
+		}
+	}
+
+	if math.Abs(rate) > c.maxRates[axis] {
+		return fmt.Errorf("rate %g exceeds maximum %g deg/sec", rate, c.maxRates[axis])
+	}
+	return nil
+}
+
+func (c *webSocketClient) enableTracking() {
+	mode, err := c.t.GetTracking()
+	if err != nil {
+		log.Errorf("unable to get tracking mode: %s", err.Error())
+	} else if mode == alpaca.NotTracking {
+		err = c.t.PutTracking(alpaca.AltAz) // need any non-NotTracking value for true
+		if err != nil {
+			log.Errorf("unable to auto-enable tracking: %s", err.Error())
+		}
+	}
+}
+
+func (c *webSocketClient) position() (*WebSocketPosition, error) {
+	ra, dec, err := c.t.GetRaDec()
+	if err != nil {
+		return nil, err
+	}
+	az, alt, err := c.t.GetAzmAlt()
+	if err != nil {
+		return nil, err
+	}
+	slewing, err := c.t.GetSlewing()
+	if err != nil {
+		return nil, err
+	}
+	tracking, err := c.t.GetTracking()
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebSocketPosition{
+		RA:       ra,
+		Dec:      dec,
+		Alt:      alt,
+		Az:       az,
+		Slewing:  slewing,
+		Tracking: tracking != alpaca.NotTracking,
+		Time:     time.Now().UTC(),
+	}, nil
+}
+
+// streams our position to subscribed clients
+func (c *webSocketClient) stream(done chan bool) {
+	var tick <-chan time.Time
+	var ticker *time.Ticker
+
+	for {
+		select {
+		case <-done:
+			if ticker != nil {
+				ticker.Stop()
+			}
+			return
+
+		case interval := <-c.subscribe:
+			if ticker != nil {
+				ticker.Stop()
+				ticker = nil
+				tick = nil
+			}
+			if interval > 0 {
+				ticker = time.NewTicker(time.Duration(interval) * time.Millisecond)
+				tick = ticker.C
+			}
+
+		case <-tick:
+			pos, err := c.position()
+			if err != nil {
+				log.Errorf("unable to get position: %s", err.Error())
+				continue
+			}
+			c.send(WebSocketReply{
+				Type:     "position",
+				OK:       true,
+				Position: pos,
+			})
+		}
+	}
+}
+
+/*
+ * oneConnListener lets us serve HTTP on a single connection and
+ * returns an error on the second Accept() after the connection closes.
+ */
+type oneConnListener struct {
+	conn   net.Conn
+	addr   net.Addr
+	closed chan bool
+	once   sync.Once
+}
+
+type notifyConn struct {
+	net.Conn
+	closed chan bool
+	once   *sync.Once
+}
+
+func (n *notifyConn) Close() error {
+	n.once.Do(func() { close(n.closed) })
+	return n.Conn.Close()
+}
+
+func newOneConnListener(conn net.Conn) *oneConnListener {
+	return &oneConnListener{
+		conn:   conn,
+		addr:   conn.LocalAddr(),
+		closed: make(chan bool),
+	}
+}
+
+func (o *oneConnListener) Accept() (net.Conn, error) {
+	if o.conn != nil {
+		conn := &notifyConn{Conn: o.conn, closed: o.closed, once: &o.once}
+		o.conn = nil
+		return conn, nil
+	}
+	<-o.closed
+	return nil, net.ErrClosed
+}
+
+func (o *oneConnListener) Close() error {
+	return nil
+}
+
+func (o *oneConnListener) Addr() net.Addr {
+	return o.addr
+}
@@ -0,0 +1,231 @@
+package telescope
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+func newWebSocketTest(t *testing.T) (*websocket.Conn, *mockAlpaca) {
+	scope, m := newMockTelescope(t, map[string]interface{}{
+		"rightascension": 5.5,
+		"declination":    -5.75,
+		"altitude":       30.0,
+		"azimuth":        180.0,
+		"slewing":        false,
+		"tracking":       true,
+		"canslewasync":   true,
+		"atpark":         false,
+		"axisrates":      []map[string]float64{{"Maximum": 4.0, "Minimum": 0.0}},
+	})
+
+	server := httptest.NewServer(NewWebSocket(false).Handler(scope))
+	t.Cleanup(server.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + WEBSOCKET_PATH
+	ws, err := websocket.Dial(url, "", server.URL)
+	assert.NoError(t, err)
+	t.Cleanup(func() { ws.Close() })
+	return ws, m
+}
+
+func wsCommand(t *testing.T, ws *websocket.Conn, req string) WebSocketReply {
+	var reply WebSocketReply
+	assert.NoError(t, websocket.Message.Send(ws, req))
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	assert.NoError(t, websocket.JSON.Receive(ws, &reply))
+	return reply
+}
+
+func TestWebSocketGoto(t *testing.T) {
+	ws, m := newWebSocketTest(t)
+
+	reply := wsCommand(t, ws, `{"id": 1, "command": "goto", "ra": 5.59, "dec": -5.39}`)
+	assert.Equal(t, WebSocketReply{Type: "reply", ID: 1, OK: true}, reply)
+	puts := m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "slewtocoordinatesasync", puts[0].API)
+	assert.Equal(t, "5.59", puts[0].Form.Get("RightAscension"))
+	assert.Equal(t, "-5.39", puts[0].Form.Get("Declination"))
+
+	// out of range targets never reach the mount
+	reply = wsCommand(t, ws, `{"id": 2, "command": "goto", "ra": 24.5, "dec": 0}`)
+	assert.False(t, reply.OK)
+	assert.Equal(t, 2, reply.ID)
+	assert.Contains(t, reply.Error, "invalid ra")
+
+	reply = wsCommand(t, ws, `{"id": 3, "command": "sync", "ra": 1.0, "dec": -91}`)
+	assert.False(t, reply.OK)
+	assert.Contains(t, reply.Error, "invalid dec")
+
+	reply = wsCommand(t, ws, `{"id": 4, "command": "goto", "ra": 1.0}`)
+	assert.False(t, reply.OK)
+	assert.Empty(t, m.Puts())
+
+	// human readable coordinates
+	reply = wsCommand(t, ws, `{"id": 5, "command": "goto", "ra": "05h35m24s", "dec": "-05°23'"}`)
+	assert.True(t, reply.OK)
+	puts = m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "5.59", puts[0].Form.Get("RightAscension"))
+	assert.Equal(t, "-5.383333333333334", puts[0].Form.Get("Declination"))
+
+	reply = wsCommand(t, ws, `{"id": 6, "command": "goto", "ra": "05h65m", "dec": 0}`)
+	assert.False(t, reply.OK)
+	assert.Contains(t, reply.Error, "invalid request")
+
+	reply = wsCommand(t, ws, `{"id": 7, "command": "sync", "ra": 1.0, "dec": 2.0}`)
+	assert.True(t, reply.OK)
+	puts = m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "synctocoordinates", puts[0].API)
+
+	// same checks as LX200 :MS# once the mount knows where it is
+	m.Set("sitelatitude", 40.0)
+	m.Set("sitelongitude", -75.0)
+	m.Set("utcdate", "2021-03-20T04:00:00Z")
+	now, _ := time.Parse(time.RFC3339, "2021-03-20T04:00:00Z")
+	meridian := strconv.FormatFloat(LocalSiderealTime(now, -75.0), 'f', -1, 64)
+
+	reply = wsCommand(t, ws, `{"id": 8, "command": "goto", "ra": `+meridian+`, "dec": -60}`)
+	assert.False(t, reply.OK)
+	assert.Contains(t, reply.Error, "below the limit")
+	reply = wsCommand(t, ws, `{"id": 9, "command": "goto", "ra": `+meridian+`, "dec": -20}`)
+	assert.True(t, reply.OK)
+	assert.Len(t, m.Puts(), 1)
+
+	m.Set("atpark", true)
+	reply = wsCommand(t, ws, `{"id": 10, "command": "goto", "ra": `+meridian+`, "dec": -20}`)
+	assert.False(t, reply.OK)
+	assert.Equal(t, "mount is parked", reply.Error)
+	m.Set("canslewasync", false)
+	reply = wsCommand(t, ws, `{"id": 11, "command": "goto", "ra": `+meridian+`, "dec": -20}`)
+	assert.False(t, reply.OK)
+	assert.Contains(t, reply.Error, "does not support")
+	assert.Empty(t, m.Puts())
+}
+
+func TestWebSocketMove(t *testing.T) {
+	ws, m := newWebSocketTest(t)
+
+	reply := wsCommand(t, ws, `{"id": 1, "command": "move", "axis": "dec", "rate": -2.5}`)
+	assert.True(t, reply.OK)
+	puts := m.Puts()
+	assert.Len(t, puts, 1)
+	assert.Equal(t, "moveaxis", puts[0].API)
+	assert.Equal(t, "1", puts[0].Form.Get("Axis"))
+	assert.Equal(t, "-2.5", puts[0].Form.Get("Rate"))
+
+	reply = wsCommand(t, ws, `{"id": 2, "command": "move", "axis": "ra", "rate": 4.5}`)
+	assert.False(t, reply.OK)
+	assert.Contains(t, reply.Error, "exceeds maximum")
+
+	reply = wsCommand(t, ws, `{"id": 3, "command": "move", "axis": "foo", "rate": 1}`)
+	assert.False(t, reply.OK)
+	assert.Empty(t, m.Puts())
+
+	reply = wsCommand(t, ws, `{"id": 4, "command": "stop"}`)
+	assert.True(t, reply.OK)
+	apis := []string{}
+	for _, p := range m.Puts() {
+		apis = append(apis, p.API)
+	}
+	assert.Equal(t, []string{"abortslew", "moveaxis", "moveaxis"}, apis)
+}
+
+func TestWebSocketErrors(t *testing.T) {
+	ws, _ := newWebSocketTest(t)
+
+	reply := wsCommand(t, ws, `{"id": 1, "command": "flip"}`)
+	assert.False(t, reply.OK)
+	assert.Contains(t, reply.Error, "unsupported command")
+
+	reply = wsCommand(t, ws, `not json`)
+	assert.False(t, reply.OK)
+	assert.Contains(t, reply.Error, "invalid request")
+
+	// connection is still usable after a bad request
+	reply = wsCommand(t, ws, `{"id": 2, "command": "tracking"}`)
+	assert.False(t, reply.OK)
+	assert.Contains(t, reply.Error, "missing enabled")
+}
+
+func TestWebSocketPosition(t *testing.T) {
+	ws, _ := newWebSocketTest(t)
+
+	reply := wsCommand(t, ws, `{"id": 1, "command": "position"}`)
+	assert.True(t, reply.OK)
+	assert.NotNil(t, reply.Position)
+	assert.Equal(t, 5.5, reply.Position.RA)
+	assert.Equal(t, -5.75, reply.Position.Dec)
+	assert.Equal(t, 30.0, reply.Position.Alt)
+	assert.Equal(t, 180.0, reply.Position.Az)
+	assert.True(t, reply.Position.Tracking)
+
+	reply = wsCommand(t, ws, `{"id": 2, "command": "subscribe", "interval": 100}`)
+	assert.True(t, reply.OK)
+	for i := 0; i < 2; i++ {
+		var pos WebSocketReply
+		_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		assert.NoError(t, websocket.JSON.Receive(ws, &pos))
+		assert.Equal(t, "position", pos.Type)
+		assert.NotNil(t, pos.Position)
+	}
+
+	// may race with one last position message
+	assert.NoError(t, websocket.Message.Send(ws, `{"id": 3, "command": "unsubscribe"}`))
+	for {
+		var msg WebSocketReply
+		_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		assert.NoError(t, websocket.JSON.Receive(ws, &msg))
+		if msg.Type == "reply" {
+			assert.Equal(t, 3, msg.ID)
+			assert.True(t, msg.OK)
+			break
+		}
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	scope, _ := newMockTelescope(t, map[string]interface{}{})
+	ws := NewWebSocket(false)
+	server := httptest.NewServer(ws.Handler(scope))
+	t.Cleanup(server.Close)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + WEBSOCKET_PATH
+
+	// pages from other sites are rejected
+	_, err := websocket.Dial(url, "", "http://evil.example.com")
+	assert.Error(t, err)
+
+	ws.AllowedOrigins = []string{"http://localhost:8080/"}
+	conn, err := websocket.Dial(url, "", "http://localhost:8080")
+	assert.NoError(t, err)
+	conn.Close()
+	_, err = websocket.Dial(url, "", "https://localhost:8080")
+	assert.Error(t, err)
+
+	ws.AllowedOrigins = []string{"*"}
+	conn, err = websocket.Dial(url, "", "http://evil.example.com")
+	assert.NoError(t, err)
+	conn.Close()
+}
+
+func TestParseWebSocketOrigins(t *testing.T) {
+	origins, err := ParseWebSocketOrigins("http://localhost:8080  https://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://localhost:8080", "https://example.com"}, origins)
+
+	origins, err = ParseWebSocketOrigins("")
+	assert.NoError(t, err)
+	assert.Empty(t, origins)
+
+	_, err = ParseWebSocketOrigins("localhost:8080")
+	assert.Error(t, err)
+	_, err = ParseWebSocketOrigins("example.com")
+	assert.Error(t, err)
+}